@@ -0,0 +1,118 @@
+package surgeon
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bketelsen/surgeon/codemods"
+)
+
+// Problem is a mistake in the configuration found by Check
+type Problem struct {
+	CodeMod int    // index of the codemod in Config.CodeMods, -1 for the configuration as a whole
+	Key     string // key of the codemod entry at fault, empty for the entry as a whole
+	Err     error
+}
+
+func (p Problem) Error() string {
+	if p.CodeMod < 0 {
+		return p.Err.Error()
+	}
+	return fmt.Sprintf("codemod %d: %v", p.CodeMod+1, p.Err)
+}
+
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+// modProblem is a problem with one key of a codemod entry
+type modProblem struct {
+	key string // empty if the problem is with the entry as a whole
+	err error
+}
+
+// Check validates c against the fork in opts.ForkRoot without needing the
+// upstream repository and returns every problem found. The codemod
+// selection of opts is checked too.
+func Check(c Config, opts Options) []Problem {
+	return newPatient(c, opts).check()
+}
+
+func (p *patient) check() []Problem {
+	var problems []Problem
+	err := p.checkSelection()
+	if err != nil {
+		problems = append(problems, Problem{CodeMod: -1, Err: err})
+	}
+	dups := duplicateIDs(p.Config.CodeMods)
+	data := p.templateData("")
+	for i, mod := range p.Config.CodeMods {
+		if slices.Contains(dups, mod.ID) {
+			problems = append(problems, Problem{CodeMod: i, Key: "id", Err: fmt.Errorf("id %q is used more than once", mod.ID)})
+		}
+		for _, problem := range checkCodeMod(mod, p.ForkRoot, data) {
+			problems = append(problems, Problem{CodeMod: i, Key: problem.key, Err: problem.err})
+		}
+	}
+	return problems
+}
+
+// checkConfig validates the codemods selected for the run before anything
+// is cloned, so mistakes in the configuration fail fast
+func (p *patient) checkConfig() error {
+	var errs []error
+	for _, problem := range p.check() {
+		if problem.CodeMod < 0 {
+			errs = append(errs, problem.Err)
+			continue
+		}
+		mod := p.Config.CodeMods[problem.CodeMod]
+		if !p.selected(mod) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("codemod %d (%s): %w", problem.CodeMod+1, mod.Description, problem.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// checkCodeMod validates a codemod entry against the fork in forkRoot
+// without needing the upstream repository. Templates are expanded with data.
+func checkCodeMod(mod CodeMod, forkRoot string, data map[string]any) []modProblem {
+	mod, problems := expandCodeMod(mod, data)
+	if len(problems) > 0 {
+		return problems
+	}
+	if mod.Mod == "" {
+		problems = append(problems, modProblem{"", errors.New("mod is required")})
+	}
+	err := codemods.ValidateMatch(mod.Match)
+	if err != nil {
+		problems = append(problems, modProblem{"match", err})
+	}
+	err = validateExpect(mod.Expect)
+	if err != nil {
+		problems = append(problems, modProblem{"expect", err})
+	}
+	if mod.Mod == "" {
+		return problems
+	}
+	cm, ok := codemods.Mods[mod.Mod]
+	if !ok {
+		return append(problems, modProblem{"mod", fmt.Errorf("unknown codemod %q", mod.Mod)})
+	}
+
+	argsKey := "args"
+	if len(mod.With) > 0 {
+		argsKey = "with"
+	}
+	args, err := codemods.ResolveArgs(cm.Args(), mod.Args, mod.With)
+	if err != nil {
+		return append(problems, modProblem{argsKey, err})
+	}
+	err = cm.Validate(codemods.DirFS(forkRoot), mod.Match, args...)
+	if err != nil {
+		problems = append(problems, modProblem{argsKey, err})
+	}
+	return problems
+}
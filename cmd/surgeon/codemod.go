@@ -4,6 +4,10 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package main
 
 import (
+	"os"
+	"path/filepath"
+
+	"github.com/bketelsen/surgeon/codemods"
 	"github.com/bketelsen/toolbox/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,3 +23,17 @@ func NewCodemodCmd(config *viper.Viper) *cobra.Command {
 	codemodCmd.AddCommand(NewCodemodDescribeCmd(config))
 	return codemodCmd
 }
+
+// registerPlugins adds the plugin codemods in the mods directory of the
+// configuration and on PATH to the available codemods
+func registerPlugins(config *viper.Viper) {
+	var dirs []string
+	if modsDir := config.GetString("modsdir"); modsDir != "" {
+		if !filepath.IsAbs(modsDir) {
+			modsDir = filepath.Join(filepath.Dir(config.GetString("config-file")), modsDir)
+		}
+		dirs = append(dirs, modsDir)
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	codemods.RegisterPlugins(dirs...)
+}
@@ -24,7 +24,14 @@ Show the usage and arguments for a codemod.`,
 				ui.Error("code mod not found", args[0])
 				return
 			}
+			if p, ok := cm.(*codemods.Plugin); ok {
+				cmd.Println("Plugin:", p.Path)
+			}
 			cmd.Println(cm.Usage())
+			if schema := cm.Args(); len(schema) > 0 {
+				cmd.Println("Args, positional in this order or by name under 'with:':")
+				cmd.Println(codemods.FormatArgs(schema))
+			}
 		},
 	}
 
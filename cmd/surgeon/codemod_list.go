@@ -21,9 +21,14 @@ func NewCodemodListCmd(_ *viper.Viper) *cobra.Command {
 			var list []List
 			// Iterate over the mods and add them to the list
 			for name, mod := range codemods.Mods {
+				source := "built in"
+				if p, ok := mod.(*codemods.Plugin); ok {
+					source = p.Path
+				}
 				list = append(list, List{
 					Name:        name,
 					Description: mod.Description(),
+					Source:      source,
 				})
 			}
 			// Print the list
@@ -39,4 +44,5 @@ func NewCodemodListCmd(_ *viper.Viper) *cobra.Command {
 type List struct {
 	Name        string `table:"name,default_sort"`
 	Description string `table:"description"`
+	Source      string `table:"source"`
 }
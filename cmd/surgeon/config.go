@@ -1,10 +1,17 @@
 package main
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/bketelsen/surgeon"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v3"
 )
 
 // setupConfig initializes the viper configuration with defaults and environment variables
@@ -29,14 +36,173 @@ func setupConfig() *viper.Viper {
 	return config
 }
 
+// newMirror returns the upstream source configured by the --offline and
+// --depth flags
+func newMirror(config *viper.Viper) *surgeon.Mirror {
+	return &surgeon.Mirror{
+		Offline: config.GetBool("offline"),
+		Depth:   config.GetInt("depth"),
+	}
+}
+
+// fragmentSuffix ends the names of the configuration fragments in the mods
+// directory, whose other files are data for the codemods
+const fragmentSuffix = ".surgeon.yaml"
+
+// configFile is one of the files the configuration was read from
+type configFile struct {
+	Path       string
+	CodeMods   int // codemods it added to the configuration
+	IgnoreList int // ignorelist entries it added
+}
+
+// ReadConfig reads the configuration in path and merges in its fragments
 func ReadConfig(path string) (surgeon.Config, error) {
+	c, _, err := readConfigFiles(path)
+	return c, err
+}
+
+// readConfigFiles reads the configuration in path and merges in the
+// fragments it includes, then those in its mods directory. It returns the
+// files read in the order they were merged. Fragments are merged depth
+// first, each file at most once.
+func readConfigFiles(path string) (surgeon.Config, []configFile, error) {
 	viper.SetConfigFile(path)
 	viper.SetConfigType("yaml")
 	err := viper.ReadInConfig()
 	if err != nil {
-		return surgeon.Config{}, err
+		return surgeon.Config{}, nil, err
 	}
 	var c surgeon.Config
-	err = viper.Unmarshal(&c)
-	return c, err
+	err = viper.Unmarshal(&c, decodeHook)
+	if err != nil {
+		return c, nil, err
+	}
+	c.Vars, err = readVars(path)
+	if err != nil {
+		return c, nil, err
+	}
+
+	l := &configLoader{
+		config: &c,
+		files:  []configFile{{Path: path, CodeMods: len(c.CodeMods), IgnoreList: len(c.IgnoreList)}},
+		seen:   map[string]bool{},
+	}
+	l.mark(path)
+	dir := filepath.Dir(path)
+	err = l.include(dir, c.Include)
+	if err != nil {
+		return c, l.files, err
+	}
+	if c.ModsDir != "" {
+		// a glob without matches is not an error, the directory may hold other files only
+		fragments, err := filepath.Glob(filepath.Join(dir, c.ModsDir, "*"+fragmentSuffix))
+		if err != nil {
+			return c, l.files, err
+		}
+		for _, f := range fragments {
+			err = l.fragment(f)
+			if err != nil {
+				return c, l.files, err
+			}
+		}
+	}
+	return c, l.files, nil
+}
+
+// decodeHook decodes a scalar string into a one element list, where viper
+// would split it on commas and break globs like "{a,b}/*.sh"
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	func(from, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf([]string{}) {
+			return data, nil
+		}
+		return []string{data.(string)}, nil
+	},
+))
+
+// readVars reads the vars of the configuration in path, keeping the case
+// of their names that viper lowercases
+func readVars(path string) (map[string]string, error) {
+	bb, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Vars map[string]string `yaml:"vars"`
+	}
+	err = yaml.Unmarshal(bb, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("reading vars: %w", err)
+	}
+	return raw.Vars, nil
+}
+
+// configLoader merges configuration fragments
+type configLoader struct {
+	config *surgeon.Config
+	files  []configFile
+	seen   map[string]bool
+}
+
+// mark records path as read and reports whether it was not read before
+func (l *configLoader) mark(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if l.seen[abs] {
+		return false
+	}
+	l.seen[abs] = true
+	return true
+}
+
+// include merges the fragments matched by the patterns, relative to dir
+func (l *configLoader) include(dir string, patterns []string) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("include %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("include %s: %w", pattern, os.ErrNotExist)
+		}
+		for _, m := range matches {
+			err = l.fragment(m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fragment merges the codemods and ignorelist entries of the fragment in
+// path, followed by the fragments it includes
+func (l *configLoader) fragment(path string) error {
+	if !l.mark(path) {
+		return nil
+	}
+	slog.Debug("Reading configuration fragment", "file", path)
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	err := v.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("reading fragment %s: %w", path, err)
+	}
+	var f surgeon.Config
+	err = v.Unmarshal(&f, decodeHook)
+	if err != nil {
+		return fmt.Errorf("reading fragment %s: %w", path, err)
+	}
+	l.config.CodeMods = append(l.config.CodeMods, f.CodeMods...)
+	l.config.IgnoreList = append(l.config.IgnoreList, f.IgnoreList...)
+	l.files = append(l.files, configFile{Path: path, CodeMods: len(f.CodeMods), IgnoreList: len(f.IgnoreList)})
+	return l.include(filepath.Dir(path), f.Include)
 }
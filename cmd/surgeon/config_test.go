@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFragments(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write(".surgeon.yaml", `upstream: https://example.com/upstream.git
+modsdir: mods
+include:
+  - shared/*.yaml
+codemods:
+  - description: main
+    mod: sed
+ignorelist:
+  - prefix: main/
+`)
+	write("shared/b.yaml", `codemods:
+  - description: shared b
+    mod: sed
+`)
+	write("shared/a.yaml", `include: [../mods/20-network.surgeon.yaml]
+codemods:
+  - description: shared a
+    mod: sed
+`)
+	write("mods/20-network.surgeon.yaml", `codemods:
+  - description: network
+    mod: sed
+ignorelist:
+  - prefix: network/
+`)
+	write("mods/10-branding.surgeon.yaml", `codemods:
+  - description: branding
+    mod: sed
+include: [../shared/b.yaml]
+`)
+	write("mods/replacement.sh", "echo hi\n")
+	write("mods/values.yaml", "codemods:\n  - description: data for a codemod\n")
+
+	c, files, err := readConfigFiles(filepath.Join(dir, ".surgeon.yaml"))
+	require.NoError(t, err)
+	var descriptions []string
+	for _, mod := range c.CodeMods {
+		descriptions = append(descriptions, mod.Description)
+	}
+	assert.Equal(t, []string{"main", "shared a", "network", "shared b", "branding"}, descriptions)
+	assert.Equal(t, "main/", c.IgnoreList[0].Prefix)
+	assert.Equal(t, "network/", c.IgnoreList[1].Prefix)
+
+	var paths []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	assert.Equal(t, []string{".surgeon.yaml", "shared/a.yaml", "mods/20-network.surgeon.yaml", "shared/b.yaml", "mods/10-branding.surgeon.yaml"}, paths)
+
+	write(".surgeon.yaml", `upstream: https://example.com/upstream.git
+include: [missing.yaml]
+`)
+	_, err = ReadConfig(filepath.Join(dir, ".surgeon.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadConfigVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".surgeon.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstream: https://example.com/upstream.git
+vars:
+  forkRaw: https://example.com/fork/raw
+  port: 8080
+codemods:
+  - mod: sed
+    args: [upstream, "{{ .vars.forkRaw }}"]
+`), 0o644))
+
+	c, err := ReadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"forkRaw": "https://example.com/fork/raw", "port": "8080"}, c.Vars)
+}
+
+func TestReadConfigScalarMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".surgeon.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstream: https://example.com/upstream.git
+codemods:
+  - mod: sed
+    match: "{ct,install}/*.sh"
+    args: [upstream, fork]
+  - mod: sed
+    match: ["{a,b}/*.sh", "!b/skip.sh"]
+    args: [upstream, fork]
+`), 0o644))
+
+	c, err := ReadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"{ct,install}/*.sh"}, c.CodeMods[0].Match)
+	assert.Equal(t, []string{"{a,b}/*.sh", "!b/skip.sh"}, c.CodeMods[1].Match)
+}
@@ -22,6 +22,7 @@ THE SOFTWARE.
 package main
 
 import (
+	"encoding/json"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -61,6 +62,10 @@ is in markdown format.`,
 			if err != nil {
 				return err
 			}
+			err = generateSchema(target)
+			if err != nil {
+				return err
+			}
 			return doc.GenMarkdownTreeCustom(cmd.Root(), o, func(_ string) string {
 				return ""
 			}, func(s string) string {
@@ -80,8 +85,20 @@ is in markdown format.`,
 	return gendocsCmd
 }
 
-func generateCodeModDocs(path string) error {
+// builtinMods returns the codemods built into surgeon, without the plugins
+// that happen to be installed
+func builtinMods() map[string]codemods.CodeMod {
+	mods := map[string]codemods.CodeMod{}
 	for name, mod := range codemods.Mods {
+		if _, ok := mod.(*codemods.Plugin); !ok {
+			mods[name] = mod
+		}
+	}
+	return mods
+}
+
+func generateCodeModDocs(path string) error {
+	for name, mod := range builtinMods() {
 		if mod.Description() == "" {
 			continue
 		}
@@ -97,7 +114,23 @@ func generateCodeModDocs(path string) error {
 		_, _ = f.WriteString("```\n")
 		_, _ = f.WriteString(mod.Usage() + "\n")
 		_, _ = f.WriteString("```\n")
+		if schema := mod.Args(); len(schema) > 0 {
+			_, _ = f.WriteString("\n## Args\n\n")
+			_, _ = f.WriteString("Positional in this order under `args:`, or by name under `with:`.\n\n")
+			_, _ = f.WriteString("```\n")
+			_, _ = f.WriteString(codemods.FormatArgs(schema))
+			_, _ = f.WriteString("```\n")
+		}
 		slog.Debug("Generated code mod docs", "name", name)
 	}
 	return nil
 }
+
+func generateSchema(path string) error {
+	bb, err := json.MarshalIndent(configSchema(builtinMods()), "", "  ")
+	if err != nil {
+		return err
+	}
+	slog.Debug("Generating configuration schema")
+	return os.WriteFile(filepath.Join(path, "surgeon.schema.json"), append(bb, '\n'), 0o644)
+}
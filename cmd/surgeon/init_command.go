@@ -57,7 +57,7 @@ Example configuration file:
 					{
 						Description: "Modify URLS",
 						Mod:         "sed",
-						Match:       "cmd/*.go",
+						Match:       []string{"cmd/*.go"},
 						Args:        []string{"github.com/upstream/repo", "github.com/myfork/repo"},
 					},
 				},
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/bketelsen/surgeon"
 	"github.com/bketelsen/toolbox/cobra"
 	"github.com/bketelsen/toolbox/ui"
 	"github.com/charmbracelet/lipgloss"
@@ -40,6 +41,9 @@ var LogLevelIDs = map[slog.Level][]string{
 func main() {
 	cmd, config := NewRootCommand()
 	cmd.AddCommand(NewInitCommand(config))
+	cmd.AddCommand(NewUpdateCommand(config))
+	cmd.AddCommand(NewValidateCommand(config))
+	cmd.AddCommand(NewSchemaCommand(config))
 	cmd.AddCommand(NewCodemodCmd(config))
 	cmd.AddCommand(NewManCommand(config))
 	cmd.AddCommand(NewGendocsCommand(config))
@@ -74,12 +78,50 @@ The surgeon command reads a configuration file in the current directory
 named '.surgeon.yaml'.  This file contains the configuration for the
 surgeon command.  The configuration file contains the upstream repository
 URL, the directory containing the code modification files, and a list of
-code modifications to apply to the forked repository.
+code modifications to apply to the forked repository.  Code modifications
+can also be split into fragments, listed under 'include' or placed in the
+mods directory as *.surgeon.yaml files.
 
-The surgeon command will clone the upstream repository into a temporary directory,
-then apply the code modifications to the cloned repository.  The contents of the
-modified repository are copied to the current directory, overwriting any existing
-files.
+The surgeon command keeps a mirror of the upstream repository in your user cache
+directory, fetches new commits into it (or uses it as is with --offline), and applies
+the code modifications in memory to the files of the upstream commit, without checking
+it out.  The modified files are copied to the current directory, replacing existing
+files that were not edited in the fork.
+
+The upstream default branch is used unless 'upstream_ref' names a branch, tag
+or commit.  After each successful run the exact upstream commit is recorded in
+'.surgeon.lock'.  Use --locked to sync to the recorded commit instead, and
+'surgeon update' to move the lock to the latest upstream commit.  The lock also
+records the commit the fork was last synced with, which 'surgeon update' leaves
+alone, and a hash of the code modifications applied to it.
+
+Files edited in the fork since the last sync are not overwritten.  Surgeon
+compares the fork with the last synced upstream commit (with the code
+modifications applied) and the new upstream, keeping fork-only edits and merging
+files changed on both sides.  If the changes overlap, surgeon refuses to sync
+and lists the conflicting files, or with --conflicts=markers writes them with
+conflict markers for you to resolve.  Conflicts left in the fork are recorded in
+the lock and reported again by later runs until the files are edited.  When the
+code modifications changed since the last sync, fork edits cannot be told apart
+and upstream changes overwrite them.
+
+Use --dry-run to preview a sync.  The upstream repository is cloned and modified
+as usual, but instead of copying files into the current directory surgeon prints
+the new and modified files along with a unified diff against your fork.
+
+Use --commit (or 'commit: {enabled: true}' in the configuration) to commit the
+synced files and the lock file.  The commit message lists the upstream commit
+range, the upstream commits it brings in and the code modifications applied.
+The author defaults to your git user.name and user.email, 'trailers' are appended
+to the message, and --branch creates a new branch for the commit.
+
+Use --only and --skip with codemod ids or names, or --tags, to apply a subset of
+the code modifications while developing them.  Codemods with 'enabled: false'
+are skipped unless --only names them.
+
+Codemods can also be plugins, executables named 'surgeon-mod-<name>' in the
+mods directory or on PATH that speak a JSON protocol over standard input and
+output.  'surgeon codemod list' shows the plugins found.
 
 Important: modifications are applied in the order they are listed in the configuration,
 and have a cumulative effect.  Be sure to verify your modifications before committing.`,
@@ -101,12 +143,17 @@ and have a cumulative effect.  Be sure to verify your modifications before commi
 				slog.Debug("Using config file from flag", "file", cfgFile)
 				config.SetConfigFile(cfgFile)
 				config.Set("config-file", cfgFile)
-				return config.ReadInConfig()
+				err := config.ReadInConfig()
+				if err != nil {
+					return err
+				}
+			} else {
+				// otherwise use the default config
+				// created or loaded by the setupConfig function
+				slog.Debug("Config Used", "file", config.ConfigFileUsed())
 			}
-			// otherwise use the default config
-			// created or loaded by the setupConfig function
-			slog.Debug("Config Used", "file", config.ConfigFileUsed())
 
+			registerPlugins(config)
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, _ []string) error {
@@ -117,8 +164,34 @@ and have a cumulative effect.  Be sure to verify your modifications before commi
 			}
 			fmt.Println(c)
 			cmd.Logger.Debug("config", "upstream", c.Upstream, "modsdir", c.ModsDir)
-			project := NewPatient(c)
-			return project.Operate()
+			opts := surgeon.Options{
+				DryRun:    config.GetBool("dry-run"),
+				Locked:    config.GetBool("locked"),
+				Conflicts: config.GetString("conflicts"),
+				Only:      config.GetStringSlice("only"),
+				Skip:      config.GetStringSlice("skip"),
+				Tags:      config.GetStringSlice("tags"),
+				Source:    newMirror(config),
+				Out:       cmd.OutOrStdout(),
+			}
+			// the flags override the commit settings from the config file
+			if cmd.Flags().Changed("commit") {
+				c.Commit.Enabled, _ = cmd.Flags().GetBool("commit")
+			}
+			if cmd.Flags().Changed("branch") {
+				c.Commit.Branch, _ = cmd.Flags().GetString("branch")
+				c.Commit.Enabled = true
+			}
+			report, err := surgeon.Run(cmd.Context(), c, opts)
+			if path := config.GetString("report"); path != "" && report != nil {
+				slog.Debug("Writing report", "file", path)
+				rerr := writeReport(path, report)
+				if rerr != nil {
+					slog.Error("writing report", "error", rerr)
+					return fmt.Errorf("writing report: %w", rerr)
+				}
+			}
+			return err
 		},
 	}
 
@@ -133,6 +206,26 @@ and have a cumulative effect.  Be sure to verify your modifications before commi
 		enumflag.New(&logLevel, "log", LogLevelIDs, enumflag.EnumCaseInsensitive),
 		"log-level",
 		"logging level [debug|info|warn|error]")
+	rootCmd.PersistentFlags().Bool("offline", false, "use the cached upstream mirror without fetching")
+	_ = config.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+	rootCmd.PersistentFlags().Int("depth", 0, "limit upstream fetches to this many commits (0 for full history)")
+	_ = config.BindPFlag("depth", rootCmd.PersistentFlags().Lookup("depth"))
+	rootCmd.Flags().Bool("dry-run", false, "print a diff of the changes instead of writing them to the fork")
+	_ = config.BindPFlag("dry-run", rootCmd.Flags().Lookup("dry-run"))
+	rootCmd.Flags().String("conflicts", surgeon.ConflictRefuse, "handling of files changed in both the fork and upstream [refuse|markers]")
+	_ = config.BindPFlag("conflicts", rootCmd.Flags().Lookup("conflicts"))
+	rootCmd.Flags().Bool("locked", false, "sync to the upstream commit recorded in .surgeon.lock")
+	_ = config.BindPFlag("locked", rootCmd.Flags().Lookup("locked"))
+	rootCmd.Flags().String("report", "", "write a JSON report of what each codemod did to this file")
+	_ = config.BindPFlag("report", rootCmd.Flags().Lookup("report"))
+	rootCmd.Flags().StringSlice("only", nil, "apply only the codemods with these ids or names")
+	_ = config.BindPFlag("only", rootCmd.Flags().Lookup("only"))
+	rootCmd.Flags().StringSlice("skip", nil, "skip the codemods with these ids or names")
+	_ = config.BindPFlag("skip", rootCmd.Flags().Lookup("skip"))
+	rootCmd.Flags().StringSlice("tags", nil, "apply only the codemods with one of these tags")
+	_ = config.BindPFlag("tags", rootCmd.Flags().Lookup("tags"))
+	rootCmd.Flags().Bool("commit", false, "commit the synced files and .surgeon.lock to the fork")
+	rootCmd.Flags().String("branch", "", "create this branch for the sync commit (implies --commit)")
 	return rootCmd, config
 }
 
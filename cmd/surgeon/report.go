@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/bketelsen/surgeon"
+)
+
+// writeReport writes the report of a run as JSON to path
+func writeReport(path string, r *surgeon.Report) error {
+	bb, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(bb, '\n'), 0o644)
+}
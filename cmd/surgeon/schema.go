@@ -0,0 +1,200 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bketelsen/surgeon"
+	"github.com/bketelsen/surgeon/codemods"
+)
+
+// schemaDescriptions documents the configuration keys in the schema, by
+// their dotted path
+var schemaDescriptions = map[string]string{
+	"upstream":                  "URL of the upstream repository",
+	"upstream_ref":              "branch, tag or commit of the upstream repository, the default branch if empty",
+	"modsdir":                   "directory in the fork containing files used by the codemods, its *.surgeon.yaml files are merged as configuration fragments",
+	"codemods":                  "code modifications, applied in order",
+	"codemods.id":               "names the codemod for --only and --skip",
+	"codemods.description":      "what the codemod is for",
+	"codemods.tags":             "tags to select the codemod with --tags",
+	"codemods.enabled":          "false skips the codemod unless --only names it",
+	"codemods.mod":              "name of the codemod, see 'surgeon codemod list'",
+	"codemods.match":            "doublestar globs of the files to modify, '!' excludes",
+	"codemods.args":             "arguments in the order the codemod declares them",
+	"codemods.with":             "arguments by name",
+	"codemods.expect":           "what the codemod has to do for the sync to succeed",
+	"codemods.expect.changes":   "required to fail if no file changes, optional if files may already be up to date",
+	"codemods.expect.min_files": "fewest matched files",
+	"codemods.expect.max_files": "most matched files, 0 for no limit",
+	"codemods.timeout":          "stop the codemod after this long, e.g. 30s",
+	"vars":                      "values for {{ .vars.name }} templates in codemod match patterns and arguments",
+	"include":                   "configuration fragments to merge, relative to this file, globs allowed",
+	"ignorelist":                "upstream files excluded from the sync",
+	"ignorelist.prefix":         "ignore every path starting with this",
+	"ignorelist.pattern":        "gitignore pattern",
+	"commit":                    "commit the sync results to the fork",
+	"commit.enabled":            "commit after a successful sync",
+	"commit.branch":             "create and switch to this branch before committing",
+	"commit.author_name":        "commit author, user.name from git config if empty",
+	"commit.author_email":       "commit author email, user.email from git config if empty",
+	"commit.trailers":           "trailers appended to the commit message",
+}
+
+// configKey returns the key of a struct field in the configuration file
+func configKey(f reflect.StructField) string {
+	for _, tag := range []string{"mapstructure", "yaml"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(f.Name)
+}
+
+// configSchema returns a JSON Schema describing the configuration file, with
+// a branch for each of mods describing its arguments
+func configSchema(mods map[string]codemods.CodeMod) map[string]any {
+	schema := typeSchema(reflect.TypeOf(surgeon.Config{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "surgeon configuration"
+	// upstream is not required so that fragments validate with the same schema
+
+	names := make([]string, 0, len(mods))
+	for name := range mods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var branches []any
+	for _, name := range names {
+		branches = append(branches, codeModSchema(name, mods[name]))
+	}
+	props := schema["properties"].(map[string]any)
+	props["codemods"].(map[string]any)["items"] = map[string]any{"oneOf": branches}
+	// an ignorelist entry takes either a prefix or a pattern
+	ignore := props["ignorelist"].(map[string]any)["items"].(map[string]any)
+	ignore["minProperties"] = 1
+	ignore["maxProperties"] = 1
+	return schema
+}
+
+// codeModSchema describes a codemod entry using the named codemod
+func codeModSchema(name string, cm codemods.CodeMod) map[string]any {
+	schema := typeSchema(reflect.TypeOf(surgeon.CodeMod{}), "codemods")
+	schema["required"] = []string{"mod", "match"}
+	schema["description"] = cm.Description()
+	props := schema["properties"].(map[string]any)
+	props["mod"] = map[string]any{"const": name, "description": schemaDescriptions["codemods.mod"]}
+	expect := props["expect"].(map[string]any)["properties"].(map[string]any)
+	expect["changes"].(map[string]any)["enum"] = []string{surgeon.ChangesRequired, surgeon.ChangesOptional}
+	props["match"] = map[string]any{
+		"description": schemaDescriptions["codemods.match"],
+		"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 1},
+		},
+	}
+
+	args := cm.Args()
+	if args == nil {
+		return schema
+	}
+	var items []any
+	with := map[string]any{}
+	for _, a := range args {
+		items = append(items, argSchema(a))
+		with[a.Name] = argSchema(a)
+	}
+	props["args"] = map[string]any{
+		"description":     schemaDescriptions["codemods.args"],
+		"type":            "array",
+		"items":           items,
+		"additionalItems": false,
+	}
+	props["with"] = map[string]any{
+		"description":          schemaDescriptions["codemods.with"],
+		"type":                 "object",
+		"properties":           with,
+		"additionalProperties": false,
+	}
+	return schema
+}
+
+// argSchema describes a codemod argument
+func argSchema(a codemods.Arg) map[string]any {
+	schema := map[string]any{"description": a.Description}
+	switch a.Type {
+	case codemods.ArgInt:
+		schema["type"] = "integer"
+	case codemods.ArgBool:
+		schema["type"] = "boolean"
+	default:
+		schema["type"] = "string"
+	}
+	if len(a.Values) > 0 {
+		schema["enum"] = a.Values
+	}
+	if a.Default != "" {
+		schema["default"] = schemaValue(a.Type, a.Default)
+	}
+	return schema
+}
+
+// schemaValue converts a default value to the JSON type of the argument
+func schemaValue(typ, value string) any {
+	switch typ {
+	case codemods.ArgInt:
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	case codemods.ArgBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// typeSchema describes the configuration type t found at path
+func typeSchema(t reflect.Type, path string) map[string]any {
+	schema := map[string]any{}
+	if d, ok := schemaDescriptions[path]; ok {
+		schema["description"] = d
+	}
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return typeSchema(t.Elem(), path)
+	case t == reflect.TypeOf(time.Duration(0)):
+		schema["type"] = "string"
+		schema["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	case t.Kind() == reflect.String:
+		schema["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+	case t.Kind() == reflect.Int:
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	case t.Kind() == reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), path)
+		delete(schema["items"].(map[string]any), "description")
+	case t.Kind() == reflect.Map:
+		schema["type"] = "object"
+		if t.Elem().Kind() == reflect.String {
+			schema["additionalProperties"] = map[string]any{"type": "string"}
+		}
+	case t.Kind() == reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			key := configKey(t.Field(i))
+			props[key] = typeSchema(t.Field(i).Type, strings.TrimPrefix(path+"."+key, "."))
+		}
+		schema["type"] = "object"
+		schema["properties"] = props
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
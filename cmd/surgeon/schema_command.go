@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Brian Ketelsen
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/bketelsen/surgeon/codemods"
+	"github.com/bketelsen/toolbox/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewSchemaCommand creates a command that prints the JSON Schema of the configuration
+func NewSchemaCommand(_ *viper.Viper) *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the configuration file",
+		Long: `The schema command prints a JSON Schema describing '.surgeon.yaml',
+including the arguments of every available codemod.
+
+Editors using yaml-language-server complete and validate the configuration
+with it.  Save it in your fork and point the configuration at it:
+
+  surgeon schema > .surgeon.schema.json
+
+  # yaml-language-server: $schema=.surgeon.schema.json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(configSchema(codemods.Mods))
+		},
+	}
+
+	return schemaCmd
+}
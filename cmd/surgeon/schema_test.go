@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bketelsen/surgeon/codemods"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSchema(t *testing.T) {
+	bb, err := json.Marshal(configSchema(codemods.Mods))
+	require.NoError(t, err)
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Items struct {
+				OneOf []struct {
+					Required   []string `json:"required"`
+					Properties map[string]struct {
+						Const      string                     `json:"const"`
+						Items      json.RawMessage            `json:"items"`
+						Properties map[string]json.RawMessage `json:"properties"`
+					} `json:"properties"`
+				} `json:"oneOf"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(bb, &schema))
+
+	assert.Empty(t, schema.Required)
+	for _, key := range []string{"upstream", "upstream_ref", "modsdir", "codemods", "ignorelist", "commit", "vars", "include"} {
+		assert.Contains(t, schema.Properties, key)
+	}
+
+	branches := schema.Properties["codemods"].Items.OneOf
+	require.Len(t, branches, len(codemods.Mods))
+	for _, branch := range branches {
+		name := branch.Properties["mod"].Const
+		cm, ok := codemods.Mods[name]
+		require.True(t, ok, name)
+		assert.Equal(t, []string{"mod", "match"}, branch.Required)
+		for key := range codeModKeys() {
+			assert.Contains(t, branch.Properties, key, name)
+		}
+		if args := cm.Args(); args != nil {
+			var items []map[string]any
+			require.NoError(t, json.Unmarshal(branch.Properties["args"].Items, &items))
+			assert.Len(t, items, len(args), name)
+			for _, a := range args {
+				assert.Contains(t, branch.Properties["with"].Properties, a.Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright © 2025 Brian Ketelsen
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package main
+
+import (
+	"github.com/bketelsen/surgeon"
+	"github.com/bketelsen/toolbox/cobra"
+	"github.com/bketelsen/toolbox/ui"
+	"github.com/spf13/viper"
+)
+
+// NewUpdateCommand creates a command that bumps the upstream commit in the lock file
+func NewUpdateCommand(config *viper.Viper) *cobra.Command {
+	updateCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update the locked upstream commit",
+		Long: `The update command resolves the configured upstream_ref (or the
+default branch of the upstream repository) to its current commit and
+records it in '.surgeon.lock'.
+
+The fork itself is not modified, and the lock still records the commit
+the fork was last synced with.  Run 'surgeon --locked' afterwards to sync
+the fork to the recorded commit.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := ReadConfig(config.GetString("config-file"))
+			if err != nil {
+				ui.Error("Specified config file not found", config.GetString("config-file"))
+				return err
+			}
+			_, err = surgeon.Update(cmd.Context(), c, surgeon.Options{
+				Source: newMirror(config),
+				Out:    cmd.OutOrStdout(),
+			})
+			return err
+		},
+	}
+
+	return updateCmd
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bketelsen/surgeon"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// configProblem is a problem found in the configuration file
+type configProblem struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+// validateConfig checks the configuration c read from files with
+// surgeon.Check and returns every problem found, positioned in the YAML
+// file it comes from
+func validateConfig(files []configFile, c surgeon.Config, opts surgeon.Options) ([]configProblem, error) {
+	var problems []configProblem
+	known := codeModKeys()
+	modProblems := map[int][]surgeon.Problem{}
+	for _, problem := range surgeon.Check(c, opts) {
+		if problem.CodeMod < 0 {
+			problems = append(problems, configProblem{Path: files[0].Path, Line: 1, Column: 1, Msg: problem.Err.Error()})
+			continue
+		}
+		modProblems[problem.CodeMod] = append(modProblems[problem.CodeMod], problem)
+	}
+	mods, ignores := 0, 0
+	for n, file := range files {
+		bb, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		err = yaml.Unmarshal(bb, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file.Path, err)
+		}
+		root := &yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			root = doc.Content[0]
+		}
+
+		var fileProblems []configProblem
+		add := func(n *yaml.Node, format string, a ...any) {
+			fileProblems = append(fileProblems, configProblem{Path: file.Path, Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, a...)})
+		}
+
+		if n == 0 && c.Upstream == "" {
+			add(root, "upstream is required")
+		}
+		if n > 0 {
+			for i := 0; i+1 < len(root.Content); i += 2 {
+				key := root.Content[i]
+				if _, ok := fragmentKeys[key.Value]; !ok && isConfigKey(key.Value) {
+					add(key, "%s is only read from the main configuration, not from fragments", key.Value)
+				}
+			}
+		}
+
+		_, ignoreNode := mappingValue(root, "ignorelist")
+		for i, ignore := range c.IgnoreList[ignores : ignores+file.IgnoreList] {
+			if (ignore.Prefix == "") == (ignore.Pattern == "") {
+				add(sequenceItem(ignoreNode, i, root), "ignorelist entry %d needs either a prefix or a pattern", i+1)
+			}
+		}
+
+		_, modsNode := mappingValue(root, "codemods")
+		for i, mod := range c.CodeMods[mods : mods+file.CodeMods] {
+			entry := sequenceItem(modsNode, i, root)
+			if entry.Kind == yaml.MappingNode {
+				for j := 0; j+1 < len(entry.Content); j += 2 {
+					key := entry.Content[j]
+					if _, ok := known[key.Value]; !ok {
+						add(key, "codemod %d: unknown key %q", i+1, key.Value)
+					}
+				}
+			}
+			for _, problem := range modProblems[mods+i] {
+				n := entry
+				if k, _ := mappingValue(entry, problem.Key); k != nil {
+					n = k
+				}
+				add(n, "codemod %d (%s): %s", i+1, mod.Mod, problem.Err)
+			}
+		}
+		mods += file.CodeMods
+		ignores += file.IgnoreList
+
+		sort.SliceStable(fileProblems, func(i, j int) bool {
+			return fileProblems[i].Line < fileProblems[j].Line
+		})
+		problems = append(problems, fileProblems...)
+	}
+	return problems, nil
+}
+
+// fragmentKeys are the top-level keys read from configuration fragments
+var fragmentKeys = map[string]struct{}{"codemods": {}, "ignorelist": {}, "include": {}}
+
+// isConfigKey reports whether key is a top-level configuration key
+func isConfigKey(key string) bool {
+	t := reflect.TypeOf(surgeon.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(configKey(t.Field(i)), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeModKeys returns the keys a codemod entry in the configuration accepts
+func codeModKeys() map[string]struct{} {
+	keys := map[string]struct{}{}
+	t := reflect.TypeOf(surgeon.CodeMod{})
+	for i := 0; i < t.NumField(); i++ {
+		keys[configKey(t.Field(i))] = struct{}{}
+	}
+	return keys
+}
+
+// mappingValue returns the key and value nodes of key in a mapping node
+func mappingValue(n *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if n == nil || n.Kind != yaml.MappingNode || key == "" {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if strings.EqualFold(n.Content[i].Value, key) {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// sequenceItem returns item i of a sequence node, or fallback if there
+// is no such item
+func sequenceItem(n *yaml.Node, i int, fallback *yaml.Node) *yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode || i >= len(n.Content) {
+		return fallback
+	}
+	return n.Content[i]
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 Brian Ketelsen
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bketelsen/surgeon"
+	"github.com/bketelsen/toolbox/cobra"
+	"github.com/bketelsen/toolbox/ui"
+	"github.com/spf13/viper"
+)
+
+// NewValidateCommand creates a command that checks the configuration without syncing
+func NewValidateCommand(config *viper.Viper) *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration without syncing",
+		Long: `The validate command checks '.surgeon.yaml' without cloning or
+fetching the upstream repository.
+
+It reports unknown codemods and configuration keys, arguments that do not
+match a codemod's argument schema, invalid match patterns and expect
+settings, files in the fork referenced by codemods (such as the replacement
+files of bashfunc and replacefile) that are missing or do not parse, and
+regular expressions and JSON or YAML paths that do not compile.
+
+Every problem is reported at once, with its line and column in the
+configuration file.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path := config.GetString("config-file")
+			c, files, err := readConfigFiles(path)
+			if err != nil {
+				ui.Error("Specified config file not found", path)
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			problems, err := validateConfig(files, c, surgeon.Options{ForkRoot: cwd})
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:%d:%d: %s\n", problem.Path, problem.Line, problem.Column, problem.Msg)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problems", len(problems))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+			return nil
+		},
+	}
+
+	return validateCmd
+}
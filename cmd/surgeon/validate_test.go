@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bketelsen/surgeon"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	fork := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(fork, "ok.sh"), []byte("function ok() {\n\techo ok\n}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(fork, "bad.sh"), []byte("function bad() {\n"), 0o644))
+
+	path := filepath.Join(fork, ".surgeon.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstream: https://example.com/upstream.git
+ignorelist:
+  - prefix: ct
+  - {}
+codemods:
+  - mod: sed
+    match: "*.sh"
+    args: [a, b]
+  - mod: frobnicate
+    match: "*.sh"
+  - mod: regex
+    match: "*.sh"
+    args: ["a(", b]
+  - mod: bashfunc
+    match: "*.sh"
+    with:
+      function: bad
+      replacement: bad.sh
+  - mod: replacefile
+    match: "*.sh"
+    args: [missing.sh]
+    colour: red
+  - mod: bashfunc
+    match: ["!*.sh"]
+    args: [ok, ok.sh]
+    expect:
+      changes: sometimes
+`), 0o644))
+
+	c, files, err := readConfigFiles(path)
+	require.NoError(t, err)
+	problems, err := validateConfig(files, c, surgeon.Options{ForkRoot: fork})
+	require.NoError(t, err)
+
+	var lines []int
+	for _, problem := range problems {
+		lines = append(lines, problem.Line)
+	}
+	assert.Equal(t, []int{4, 9, 13, 16, 21, 22, 24, 26}, lines)
+	assert.Contains(t, problems[1].Msg, `unknown codemod "frobnicate"`)
+	assert.Contains(t, problems[5].Msg, `unknown key "colour"`)
+	assert.Equal(t, 5, problems[5].Column)
+}
+
+func TestValidateFragments(t *testing.T) {
+	fork := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(fork, "mods"), 0o755))
+	path := filepath.Join(fork, ".surgeon.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstream: https://example.com/upstream.git
+modsdir: mods
+codemods:
+  - mod: sed
+    match: "*.sh"
+    args: [a, b]
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(fork, "mods", "team.surgeon.yaml"), []byte(`upstream: https://example.com/other.git
+ignorelist:
+  - prefix: ct
+codemods:
+  - mod: sed
+    match: "*.sh"
+    args: [a]
+`), 0o644))
+
+	c, files, err := readConfigFiles(path)
+	require.NoError(t, err)
+	problems, err := validateConfig(files, c, surgeon.Options{ForkRoot: fork})
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	for _, problem := range problems {
+		assert.Equal(t, filepath.Join(fork, "mods", "team.surgeon.yaml"), problem.Path)
+	}
+	assert.Equal(t, 1, problems[0].Line)
+	assert.Equal(t, 7, problems[1].Line)
+}
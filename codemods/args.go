@@ -0,0 +1,134 @@
+package codemods
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// argument types
+const (
+	ArgString = "string"
+	ArgInt    = "int"
+	ArgBool   = "bool"
+	ArgPath   = "path" // a file in the fork, relative to its root
+)
+
+// Arg describes an argument of a codemod. Arguments can be given in the
+// order they are declared in (args:) or by name (with:).
+type Arg struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Values      []string `json:"values,omitempty"` // allowed values, any if empty
+	Description string   `json:"description"`
+}
+
+// check reports whether value is valid for the argument
+func (a Arg) check(value string) error {
+	var err error
+	switch a.Type {
+	case ArgInt:
+		_, err = strconv.Atoi(value)
+	case ArgBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("argument %q must be of type %s, not %q", a.Name, a.Type, value)
+	}
+	if len(a.Values) > 0 && !slices.Contains(a.Values, value) {
+		return fmt.Errorf("argument %q must be one of %s, not %q", a.Name, strings.Join(a.Values, ", "), value)
+	}
+	return nil
+}
+
+// ResolveArgs merges the positional args and the named with arguments into
+// the positional form codemods are applied with, checking them against the
+// schema and filling in defaults. Optional arguments after the last one
+// given are left out.
+func ResolveArgs(schema []Arg, args []string, with map[string]any) ([]string, error) {
+	if schema == nil {
+		if len(with) > 0 {
+			return nil, fmt.Errorf("codemod does not take named arguments")
+		}
+		return args, nil
+	}
+	if len(args) > len(schema) {
+		return nil, fmt.Errorf("codemod takes at most %d arguments, got %d", len(schema), len(args))
+	}
+
+	values := make([]string, len(schema))
+	set := make([]bool, len(schema))
+	copy(values, args)
+	for i := range args {
+		set[i] = true
+	}
+
+	names := make([]string, 0, len(with))
+	for name := range with {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i := slices.IndexFunc(schema, func(a Arg) bool { return a.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+		if set[i] {
+			return nil, fmt.Errorf("argument %q is given both in args and with", name)
+		}
+		switch v := with[name].(type) {
+		case string, bool, int, int64, uint64, float64:
+			values[i] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("argument %q must be a single value", name)
+		}
+		set[i] = true
+	}
+
+	for i, a := range schema {
+		switch {
+		case set[i]:
+			if values[i] == "" && !a.Required {
+				continue
+			}
+			if err := a.check(values[i]); err != nil {
+				return nil, err
+			}
+		case a.Required:
+			return nil, fmt.Errorf("missing required argument %q", a.Name)
+		case a.Default != "":
+			values[i] = a.Default
+			set[i] = true
+		}
+	}
+
+	n := len(values)
+	for n > 0 && !set[n-1] {
+		n--
+	}
+	return values[:n], nil
+}
+
+// FormatArgs describes the arguments of a schema, one per line
+func FormatArgs(schema []Arg) string {
+	var sb strings.Builder
+	for i, a := range schema {
+		var attrs []string
+		attrs = append(attrs, a.Type)
+		if a.Required {
+			attrs = append(attrs, "required")
+		}
+		if a.Default != "" {
+			attrs = append(attrs, "default "+strconv.Quote(a.Default))
+		}
+		if len(a.Values) > 0 {
+			attrs = append(attrs, "one of "+strings.Join(a.Values, ", "))
+		}
+		fmt.Fprintf(&sb, "\t%d. %s (%s)\n\t   %s\n", i+1, a.Name, strings.Join(attrs, ", "), a.Description)
+	}
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package codemods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveArgs(t *testing.T) {
+	schema := []Arg{
+		{Name: "action", Type: ArgString, Required: true, Values: []string{"set", "del"}},
+		{Name: "key", Type: ArgString, Required: true},
+		{Name: "value", Type: ArgString},
+		{Name: "limit", Type: ArgInt, Default: "0"},
+		{Name: "quiet", Type: ArgBool},
+	}
+	tests := []struct {
+		name        string
+		args        []string
+		with        map[string]any
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "Positional",
+			args:     []string{"set", "a.b", "x"},
+			expected: []string{"set", "a.b", "x", "0"},
+		},
+		{
+			name:     "Named",
+			with:     map[string]any{"action": "del", "key": "a.b", "quiet": true},
+			expected: []string{"del", "a.b", "", "0", "true"},
+		},
+		{
+			name:     "Mixed",
+			args:     []string{"set"},
+			with:     map[string]any{"key": "a", "limit": 3},
+			expected: []string{"set", "a", "", "3"},
+		},
+		{
+			name:        "Missing required",
+			with:        map[string]any{"action": "set"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown name",
+			args:        []string{"set", "a"},
+			with:        map[string]any{"colour": "red"},
+			expectError: true,
+		},
+		{
+			name:        "Given twice",
+			args:        []string{"set", "a"},
+			with:        map[string]any{"key": "b"},
+			expectError: true,
+		},
+		{
+			name:        "Not allowed value",
+			args:        []string{"append", "a"},
+			expectError: true,
+		},
+		{
+			name:        "Wrong type",
+			with:        map[string]any{"action": "set", "key": "a", "limit": "many"},
+			expectError: true,
+		},
+		{
+			name:        "Not a single value",
+			with:        map[string]any{"action": "set", "key": "a", "value": []any{"x"}},
+			expectError: true,
+		},
+		{
+			name:        "Too many positional",
+			args:        []string{"set", "a", "b", "1", "true", "extra"},
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := ResolveArgs(schema, tt.args, tt.with)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestResolveArgsWithoutSchema(t *testing.T) {
+	args, err := ResolveArgs(nil, []string{"a", "b"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, args)
+
+	_, err = ResolveArgs(nil, nil, map[string]any{"a": "b"})
+	assert.Error(t, err)
+}
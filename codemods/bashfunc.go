@@ -2,11 +2,10 @@ package codemods
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strings"
 
 	"mvdan.cc/sh/syntax"
@@ -21,31 +20,47 @@ type BashFunc struct{}
 // assert that Sed implements CodeMod
 var _ CodeMod = BashFunc{}
 
-func (s BashFunc) Apply(source, target, match string, args ...string) error {
-	slog.Info("Applying bash function replacer", "source", source, "target", target, "match", match, "args", args)
-	sourceMatches := filepath.Join(source, match)
-	matches, err := filepath.Glob(sourceMatches)
+func (s BashFunc) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying bash function replacer", "match", match, "args", args)
+	replacement, err := readForkFile(fork, args[1])
 	if err != nil {
-		return fmt.Errorf("globbing source: %w", err)
+		return Result{}, fmt.Errorf("applying bash function replacer: %w", err)
 	}
-	for _, m := range matches {
-		replacement := filepath.Join(target, args[1])
-		err = replaceFunctionInFile(args[0], replacement, m)
-		if err != nil {
-			return fmt.Errorf("applying bash function replacer: %w", err)
-		}
+
+	res, err := modifyMatches(ctx, files, match, func(_ string, b []byte) ([]byte, int, error) {
+		out, err := replaceFunction(args[0], replacement, b)
+		return out, 1, err
+	})
+	if err != nil {
+		return res, fmt.Errorf("applying bash function replacer: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (s BashFunc) Validate(_, _, _ string, args ...string) error {
+func (s BashFunc) Validate(fork fs.FS, _ []string, args ...string) error {
 	if len(args) != 2 {
 		return errors.New("bashfunc requires two arguments")
 	}
+	bb, err := readForkFile(fork, args[1])
+	if err != nil {
+		return fmt.Errorf("reading replacement function: %w", err)
+	}
+	_, err = syntax.NewParser().Parse(bytes.NewReader(bb), args[1])
+	if err != nil {
+		return fmt.Errorf("parsing replacement function: %w", err)
+	}
 	return nil
 }
 
+func (s BashFunc) Args() []Arg {
+	return []Arg{
+		{Name: "function", Type: ArgString, Required: true, Description: "name of the function to replace"},
+		{Name: "replacement", Type: ArgPath, Required: true, Description: "file in your fork containing the replacement function"},
+	}
+}
+
 func (s BashFunc) Description() string {
 	return "Replace a bash function with another"
 }
@@ -55,10 +70,6 @@ func (s BashFunc) Usage() string {
 This codemod searches for a bash function in the matched file(s)
 and replaces it with another function.
 
-Args (2 required):
-	1. The name of the function to replace
-	2. The path to the file (in your fork) containing the replacement function
-
 Example:
 	upstream: https://github.com/community-scripts/ProxmoxVE
 	modsdir: codemods
@@ -133,31 +144,3 @@ func replaceFunction(name string, replacementContent, fileContent []byte) ([]byt
 	}
 	return result.Bytes(), nil
 }
-
-func replaceFunctionInFile(name, replacementPath, filePath string) error {
-	// Read the original file content
-	fileContent, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	// Read the replacement content
-	replacementContent, err := os.ReadFile(replacementPath)
-	if err != nil {
-		return err
-	}
-
-	// Perform the replacement
-	modifiedContent, err := replaceFunction(name, replacementContent, fileContent)
-	if err != nil {
-		return err
-	}
-
-	// Write the modified content back to the file
-	err = os.WriteFile(filePath, modifiedContent, 0o644)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
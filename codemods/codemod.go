@@ -1,10 +1,119 @@
 package codemods
 
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+)
+
 type CodeMod interface {
-	Apply(source string, target string, match string, args ...string) error
-	Validate(source string, target string, match string, args ...string) error
+	// Apply changes the files matched by match. The files of the fork the
+	// arguments refer to are read from fork.
+	Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error)
+	Validate(fork fs.FS, match []string, args ...string) error
+	Args() []Arg // nil if the codemod only takes positional arguments
 	Description() string
 	Usage() string
 }
 
+// Result reports what a codemod did
+type Result struct {
+	Matched     int      `json:"matched"`            // files the codemod was applied to
+	Changed     int      `json:"changed"`            // files whose content changed
+	Files       []string `json:"files,omitempty"`    // changed files, relative to the source
+	Bytes       int      `json:"bytes"`              // bytes rewritten in the changed files
+	Occurrences int      `json:"occurrences"`        // replacements or insertions made
+	Warnings    []string `json:"warnings,omitempty"` // problems that did not stop the codemod
+}
+
+// Warn adds a warning to the result
+func (r *Result) Warn(format string, a ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, a...))
+}
+
+// record adds a changed file to the result. The bytes rewritten are those
+// between the common prefix and suffix of the old and new content.
+func (r *Result) record(name string, before, after []byte, occurrences int) {
+	r.Changed++
+	r.Files = append(r.Files, name)
+	r.Occurrences += occurrences
+
+	n := min(len(before), len(after))
+	prefix := 0
+	for prefix < n && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	r.Bytes += max(len(before), len(after)) - prefix - suffix
+}
+
 var Mods = map[string]CodeMod{}
+
+// loggerKey is the context key of the logger codemods log to
+type loggerKey struct{}
+
+// WithLogger returns a context in which codemods log to l
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the logger of ctx set by WithLogger, or the default
+// logger
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// modifyMatches rewrites every file in files matched by match with modify,
+// which is given the path of the file and returns the new content and the
+// occurrences it replaced. It stops when ctx is done.
+func modifyMatches(ctx context.Context, files FS, match []string, modify func(name string, content []byte) ([]byte, int, error)) (Result, error) {
+	matches, err := Glob(files, match)
+	if err != nil {
+		return Result{}, fmt.Errorf("globbing source: %w", err)
+	}
+	res := Result{Matched: len(matches)}
+	for _, m := range matches {
+		err = ctx.Err()
+		if err != nil {
+			return res, err
+		}
+		err = res.rewriteFile(files, m, func(b []byte) ([]byte, int, error) {
+			return modify(m, b)
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// rewriteFile replaces the content of the file name in files with the
+// result of modify. The file is only rewritten, and recorded in the
+// result, if the content changed.
+func (r *Result) rewriteFile(files FS, name string, modify func([]byte) ([]byte, int, error)) error {
+	fileData, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+	modifiedData, occurrences, err := modify(fileData)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(fileData, modifiedData) {
+		return nil
+	}
+	err = files.WriteFile(name, modifiedData, 0o644)
+	if err != nil {
+		return err
+	}
+	r.record(name, fileData, modifiedData, occurrences)
+	return nil
+}
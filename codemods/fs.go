@@ -0,0 +1,257 @@
+package codemods
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS is a file system codemods read and change files in. Paths are slash
+// separated and relative to its root, as in io/fs. DirFS, MemFS and Tree
+// implement it.
+type FS interface {
+	fs.FS
+	// WriteFile replaces the content of the file name, keeping the
+	// permissions of an existing file. A new file is created with perm.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Remove removes the file name
+	Remove(name string) error
+}
+
+// DirFS is the FS of the files below a directory on disk
+type DirFS string
+
+// assert that DirFS implements FS
+var _ FS = DirFS("")
+
+func (d DirFS) fsys() fs.FS {
+	return os.DirFS(string(d))
+}
+
+// path returns the OS path of name, which must be a valid fs path
+func (d DirFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(d), filepath.FromSlash(name)), nil
+}
+
+func (d DirFS) Open(name string) (fs.File, error) {
+	return d.fsys().Open(name)
+}
+
+func (d DirFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(d.fsys(), name)
+}
+
+func (d DirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(d.fsys(), name)
+}
+
+func (d DirFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(d.fsys(), name)
+}
+
+func (d DirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	p, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, perm)
+}
+
+func (d DirFS) Remove(name string) error {
+	p, err := d.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// forkDir returns the directory of a fork on disk, or an empty string if
+// the fork is not a DirFS
+func forkDir(fork fs.FS) string {
+	if d, ok := fork.(DirFS); ok {
+		return string(d)
+	}
+	return ""
+}
+
+// readForkFile reads the file of the fork named by a codemod argument
+func readForkFile(fork fs.FS, name string) ([]byte, error) {
+	return fs.ReadFile(fork, path.Clean(filepath.ToSlash(name)))
+}
+
+// MemFile is a file in a MemFS
+type MemFile struct {
+	Data []byte
+	Mode fs.FileMode // permissions, 0o644 if zero
+}
+
+// MemFS is an in-memory FS of files by path. Directories are implied by
+// the files in them.
+type MemFS map[string]*MemFile
+
+// assert that MemFS implements FS
+var _ FS = MemFS{}
+
+func (m MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := m[name]; ok {
+		return newMemFile(name, f.Data, f.mode()), nil
+	}
+	entries, ok := m.entries(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memDir{info: dirInfo(name), entries: entries}, nil
+}
+
+// entries lists the directory name, reporting false if there is none
+func (m MemFS) entries(name string) ([]fs.DirEntry, bool) {
+	byName := map[string]fs.DirEntry{}
+	for p, f := range m {
+		child, isFile, ok := childOf(name, p)
+		if !ok {
+			continue
+		}
+		if isFile {
+			byName[child] = fileInfo{name: child, size: int64(len(f.Data)), mode: f.mode()}
+		} else {
+			byName[child] = dirInfo(child)
+		}
+	}
+	if len(byName) == 0 && name != "." {
+		return nil, false
+	}
+	return sortedEntries(byName), true
+}
+
+func (m MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := m[name]; ok {
+		f.Data = bytes.Clone(data)
+		return nil
+	}
+	m[name] = &MemFile{Data: bytes.Clone(data), Mode: perm}
+	return nil
+}
+
+func (m MemFS) Remove(name string) error {
+	if _, ok := m[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m, name)
+	return nil
+}
+
+func (f *MemFile) mode() fs.FileMode {
+	if f.Mode == 0 {
+		return 0o644
+	}
+	return f.Mode.Perm()
+}
+
+// childOf returns the first element of p below the directory dir, and
+// whether it is p itself rather than a directory holding it
+func childOf(dir, p string) (string, bool, bool) {
+	rest := p
+	if dir != "." {
+		var ok bool
+		rest, ok = strings.CutPrefix(p, dir+"/")
+		if !ok {
+			return "", false, false
+		}
+	}
+	child, _, nested := strings.Cut(rest, "/")
+	return child, !nested, true
+}
+
+// sortedEntries returns the entries of a directory sorted by name
+func sortedEntries(byName map[string]fs.DirEntry) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+// fileInfo describes a file or directory of an in-memory file system
+type fileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func dirInfo(name string) fileInfo {
+	return fileInfo{name: path.Base(name), mode: fs.ModeDir | 0o755}
+}
+
+func (fi fileInfo) Name() string               { return fi.name }
+func (fi fileInfo) Size() int64                { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode          { return fi.mode }
+func (fi fileInfo) ModTime() time.Time         { return time.Time{} }
+func (fi fileInfo) IsDir() bool                { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any                   { return nil }
+func (fi fileInfo) Type() fs.FileMode          { return fi.mode.Type() }
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+// memFile is an open file of an in-memory file system
+type memFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func newMemFile(name string, data []byte, mode fs.FileMode) *memFile {
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		info:   fileInfo{name: path.Base(name), size: int64(len(data)), mode: mode},
+	}
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory of an in-memory file system
+type memDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
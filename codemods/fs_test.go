@@ -0,0 +1,95 @@
+package codemods
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemFS(t *testing.T) {
+	files := MemFS{
+		"README.md":   {Data: []byte("readme")},
+		"ct/app.sh":   {Data: []byte("echo app"), Mode: 0o755},
+		"ct/sub/x.sh": {Data: []byte("echo x")},
+	}
+	require.NoError(t, fstest.TestFS(files, "README.md", "ct/app.sh", "ct/sub/x.sh"))
+
+	require.NoError(t, files.WriteFile("ct/app.sh", []byte("echo fork"), 0o644))
+	fi, err := fs.Stat(files, "ct/app.sh")
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o755), fi.Mode(), "existing files keep their mode")
+	require.NoError(t, files.Remove("ct/sub/x.sh"))
+	_, err = fs.Stat(files, "ct/sub")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorIs(t, files.Remove("ct/sub/x.sh"), fs.ErrNotExist)
+	assert.Error(t, files.WriteFile("../x", nil, 0o644))
+}
+
+func TestDirFS(t *testing.T) {
+	root := t.TempDir()
+	files := DirFS(root)
+	require.NoError(t, files.WriteFile("ct/app.sh", []byte("echo app"), 0o755))
+	bb, err := os.ReadFile(filepath.Join(root, "ct", "app.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo app", string(bb))
+	require.NoError(t, fstest.TestFS(files, "ct/app.sh"))
+	require.NoError(t, files.Remove("ct/app.sh"))
+	assert.NoFileExists(t, filepath.Join(root, "ct", "app.sh"))
+	assert.Error(t, files.WriteFile("../outside", nil, 0o644))
+}
+
+func TestGitFS(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	for name, content := range map[string]string{
+		"install.sh":      "curl https://upstream/raw/main/install.sh\n",
+		"misc/build.func": "echo upstream\n",
+	} {
+		f, err := w.Filesystem.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		_, err = w.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := w.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Upstream", Email: "upstream@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	commit, err := r.CommitObject(hash)
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+
+	files := GitFS(tree)
+	require.NoError(t, fstest.TestFS(files, "install.sh", "misc/build.func"))
+	_, err = fs.Stat(files, "missing/file")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// codemods change a git tree through a Tree, which cannot be flushed
+	changes := NewTree(files)
+	res, err := Sed{}.Apply(context.Background(), changes, MemFS{}, []string{"**/*.sh"}, "upstream", "fork")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"install.sh"}, res.Files)
+	bb, err := fs.ReadFile(changes, "install.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "curl https://fork/raw/main/install.sh\n", string(bb))
+	bb, err = fs.ReadFile(files, "install.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "curl https://upstream/raw/main/install.sh\n", string(bb))
+	assert.Error(t, changes.Flush())
+}
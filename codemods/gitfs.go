@@ -0,0 +1,146 @@
+package codemods
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gitFS is the read-only file system of a git tree
+type gitFS struct {
+	tree *object.Tree
+}
+
+// GitFS returns the files of a git tree as a read-only file system.
+// Submodules are empty directories and symbolic links files holding
+// their target, as git stores them.
+func GitFS(tree *object.Tree) fs.FS {
+	return gitFS{tree: tree}
+}
+
+// entry returns the tree entry of name, nil for the root
+func (g gitFS) entry(op, name string) (*object.TreeEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil, nil
+	}
+	e, err := g.tree.FindEntry(name)
+	if err != nil {
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return e, nil
+}
+
+func (g gitFS) Open(name string) (fs.File, error) {
+	e, err := g.entry("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.Mode == filemode.Dir || e.Mode == filemode.Submodule {
+		entries, err := g.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{info: dirInfo(name), entries: entries}, nil
+	}
+	bb, err := g.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return newMemFile(name, bb, fileMode(e.Mode)), nil
+}
+
+func (g gitFS) ReadFile(name string) ([]byte, error) {
+	e, err := g.entry("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.Mode == filemode.Dir || e.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	f, err := g.tree.TreeEntryFile(e)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (g gitFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := g.entry("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && e.Mode == filemode.Submodule {
+		return []fs.DirEntry{}, nil
+	}
+	tree := g.tree
+	if e != nil {
+		if e.Mode != filemode.Dir {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		tree, err = g.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+	}
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, te := range tree.Entries {
+		if te.Mode == filemode.Dir || te.Mode == filemode.Submodule {
+			entries = append(entries, dirInfo(te.Name))
+		} else {
+			entries = append(entries, gitEntry{fsys: g, name: path.Join(name, te.Name), mode: fileMode(te.Mode)})
+		}
+	}
+	return entries, nil
+}
+
+// gitEntry is a file in a directory listing of a git tree, whose size is
+// only looked up when asked for
+type gitEntry struct {
+	fsys gitFS
+	name string
+	mode fs.FileMode
+}
+
+func (e gitEntry) Name() string               { return path.Base(e.name) }
+func (e gitEntry) IsDir() bool                { return false }
+func (e gitEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e gitEntry) Info() (fs.FileInfo, error) { return e.fsys.Stat(e.name) }
+
+func (g gitFS) Stat(name string) (fs.FileInfo, error) {
+	e, err := g.entry("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.Mode == filemode.Dir || e.Mode == filemode.Submodule {
+		return dirInfo(name), nil
+	}
+	size, err := g.tree.Size(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{name: e.Name, size: size, mode: fileMode(e.Mode)}, nil
+}
+
+// fileMode returns the file system mode of a git file mode
+func fileMode(m filemode.FileMode) fs.FileMode {
+	mode, err := m.ToOSFileMode()
+	if err != nil {
+		return 0o644
+	}
+	return mode
+}
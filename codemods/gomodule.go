@@ -0,0 +1,238 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Mods["gomodule"] = GoModule{}
+}
+
+type GoModule struct{}
+
+// assert that GoModule implements CodeMod
+var _ CodeMod = GoModule{}
+
+func (s GoModule) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying gomodule", "match", match, "args", args)
+
+	var res Result
+	skip := func(d fs.DirEntry) bool {
+		return d.Name() == ".git" || d.Name() == "vendor" || d.Name() == "testdata"
+	}
+	err := walkMatches(files, match, skip, func(name string) error {
+		if path.Base(name) != "go.mod" && !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		res.Matched++
+		return rewriteGoFile(log, &res, files, args[0], args[1], name)
+	})
+	if err != nil {
+		return res, fmt.Errorf("rewriting go module: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s GoModule) Validate(_ fs.FS, _ []string, args ...string) error {
+	if len(args) != 2 {
+		return errors.New("gomodule requires two arguments")
+	}
+	if args[0] == "" || args[1] == "" {
+		return errors.New("gomodule module paths must not be empty")
+	}
+	return nil
+}
+
+func (s GoModule) Args() []Arg {
+	return []Arg{
+		{Name: "old", Type: ArgString, Required: true, Description: "current module path"},
+		{Name: "new", Type: ArgString, Required: true, Description: "new module path"},
+	}
+}
+
+func (s GoModule) Description() string {
+	return "Rewrite a Go module path and its imports"
+}
+
+func (s GoModule) Usage() string {
+	return `Rewrite a Go module path and its imports.
+This codemod changes the module path in the module, require, replace
+and exclude directives of go.mod files and every import of the module
+(or its packages) in Go source files. Go files are parsed and printed
+with go/format, so comments, string literals and import grouping are
+left alone, and the imports of each group are sorted again as gofmt
+does.
+
+Matched directories are searched recursively, skipping .git, vendor
+and testdata directories. Matched files are rewritten directly.
+
+Example:
+	upstream: https://github.com/upstream/project
+	modsdir: codemods
+	codemods:
+	- description: Re-home the module
+		mod: gomodule
+		match: .
+		args:
+		- github.com/upstream/project
+		- github.com/myfork/project
+	`
+}
+
+// rewriteImportPath returns the import path with the module prefix old
+// replaced by newpath, and whether it belongs to the module at all.
+func rewriteImportPath(old, newpath, path string) (string, bool) {
+	if path == old {
+		return newpath, true
+	}
+	if strings.HasPrefix(path, old+"/") {
+		return newpath + path[len(old):], true
+	}
+	return path, false
+}
+
+// rewriteGoImports rewrites imports of module old in a Go source file
+// and returns the number of imports rewritten. It returns nil if nothing
+// was changed.
+func rewriteGoImports(old, newpath string, fileContent []byte) ([]byte, int, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", fileContent, parser.ParseComments)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var changed int
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		if rewritten, ok := rewriteImportPath(old, newpath, path); ok {
+			imp.Path.Value = strconv.Quote(rewritten)
+			changed++
+		}
+	}
+	if changed == 0 {
+		return nil, 0, nil
+	}
+
+	// the rewritten paths may no longer be in order
+	ast.SortImports(fset, f)
+	var buf bytes.Buffer
+	err = format.Node(&buf, fset, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), changed, nil
+}
+
+// rewriteGoMod rewrites the paths of module old, and its nested modules,
+// in the module, require, replace and exclude directives of a go.mod file
+// and returns the number of paths rewritten. It returns nil if nothing
+// was changed.
+func rewriteGoMod(old, newpath string, fileContent []byte) ([]byte, int) {
+	lines := strings.Split(string(fileContent), "\n")
+	var block string // directive of the enclosing ( ) block
+	var changed int
+	for i, line := range lines {
+		code, _, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		verb, args := block, fields
+		switch {
+		case len(fields) == 0:
+			continue
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			verb, args = fields[0], fields[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		var paths []string
+		switch verb {
+		case "module", "require", "exclude":
+			paths = args[:1]
+		case "replace":
+			// old [version] => new [version], new may be a directory
+			paths = args[:1]
+			if j := slices.Index(args, "=>"); j >= 0 && j+1 < len(args) {
+				paths = append(paths, args[j+1])
+			}
+		}
+		at := 0
+		for _, p := range paths {
+			j := at + strings.Index(line[at:], p)
+			unquoted := p
+			if u, err := strconv.Unquote(p); err == nil {
+				unquoted = u
+			}
+			rewritten, ok := rewriteImportPath(old, newpath, unquoted)
+			if !ok {
+				at = j + len(p)
+				continue
+			}
+			line = line[:j] + rewritten + line[j+len(p):]
+			at = j + len(rewritten)
+			changed++
+		}
+		lines[i] = line
+	}
+	if changed == 0 {
+		return nil, 0
+	}
+	return []byte(strings.Join(lines, "\n")), changed
+}
+
+func rewriteGoFile(log *slog.Logger, res *Result, files FS, old, newpath, filePath string) error {
+	isMod := path.Base(filePath) == "go.mod"
+	changed := res.Changed
+	err := res.rewriteFile(files, filePath, func(b []byte) ([]byte, int, error) {
+		var modifiedData []byte
+		var occurrences int
+		var err error
+		if isMod {
+			modifiedData, occurrences = rewriteGoMod(old, newpath, b)
+		} else {
+			modifiedData, occurrences, err = rewriteGoImports(old, newpath, b)
+		}
+		if modifiedData == nil {
+			return b, 0, err
+		}
+		return modifiedData, occurrences, err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	switch {
+	case res.Changed > changed:
+		log.Debug("Rewrote module path", "file", filePath)
+	case isMod:
+		res.Warn("%s does not refer to module %s", filePath, old)
+	}
+	return nil
+}
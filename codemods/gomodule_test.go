@@ -0,0 +1,174 @@
+package codemods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteGoImports(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileContent string
+		expected    string
+		occurrences int
+	}{
+		{
+			name: "Rewrite grouped imports",
+			fileContent: `package main
+
+import (
+	"fmt"
+
+	"github.com/upstream/project"
+	"github.com/upstream/project/pkg/util"
+	other "github.com/upstream/projectx"
+)
+
+// see github.com/upstream/project for details
+var url = "github.com/upstream/project"
+`,
+			expected: `package main
+
+import (
+	"fmt"
+
+	"github.com/myfork/project"
+	"github.com/myfork/project/pkg/util"
+	other "github.com/upstream/projectx"
+)
+
+// see github.com/upstream/project for details
+var url = "github.com/upstream/project"
+`,
+			occurrences: 2,
+		},
+		{
+			name: "Rewrite single import",
+			fileContent: `package main
+
+import "github.com/upstream/project/cmd"
+`,
+			expected: `package main
+
+import "github.com/myfork/project/cmd"
+`,
+			occurrences: 1,
+		},
+		{
+			name: "Sort rewritten imports",
+			fileContent: `package main
+
+import (
+	"github.com/other/lib"
+	"github.com/upstream/project/pkg"
+)
+`,
+			expected: `package main
+
+import (
+	"github.com/myfork/project/pkg"
+	"github.com/other/lib"
+)
+`,
+			occurrences: 1,
+		},
+		{
+			name: "No matching imports",
+			fileContent: `package main
+
+import "fmt"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, occurrences, err := rewriteGoImports("github.com/upstream/project", "github.com/myfork/project", []byte(tt.fileContent))
+			require.NoError(t, err)
+			if tt.expected == "" {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, tt.expected, string(result))
+			assert.Equal(t, tt.occurrences, occurrences)
+		})
+	}
+}
+
+func TestRewriteGoMod(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileContent string
+		expected    string
+		occurrences int
+	}{
+		{
+			name:        "Rewrite module directive",
+			fileContent: "module github.com/upstream/project\n\ngo 1.24\n",
+			expected:    "module github.com/myfork/project\n\ngo 1.24\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Rewrite quoted module directive",
+			fileContent: "module \"github.com/upstream/project\"\n",
+			expected:    "module github.com/myfork/project\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Different module",
+			fileContent: "module github.com/upstream/projectx\n",
+		},
+		{
+			name: "Rewrite require and replace directives",
+			fileContent: `module github.com/upstream/tool
+
+require github.com/upstream/project v1.2.0
+
+require (
+	github.com/upstream/project/sub v0.1.0 // indirect
+	github.com/upstream/projectx v1.0.0
+)
+
+replace github.com/upstream/project v1.2.0 => github.com/upstream/project v1.3.0
+
+replace (
+	github.com/upstream/project/sub => ../sub
+)
+
+exclude github.com/upstream/project v1.1.0
+`,
+			expected: `module github.com/upstream/tool
+
+require github.com/myfork/project v1.2.0
+
+require (
+	github.com/myfork/project/sub v0.1.0 // indirect
+	github.com/upstream/projectx v1.0.0
+)
+
+replace github.com/myfork/project v1.2.0 => github.com/myfork/project v1.3.0
+
+replace (
+	github.com/myfork/project/sub => ../sub
+)
+
+exclude github.com/myfork/project v1.1.0
+`,
+			occurrences: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, occurrences := rewriteGoMod("github.com/upstream/project", "github.com/myfork/project", []byte(tt.fileContent))
+			if tt.expected == "" {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, tt.expected, string(result))
+			assert.Equal(t, tt.occurrences, occurrences)
+		})
+	}
+}
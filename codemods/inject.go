@@ -5,11 +5,10 @@ package codemods
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strconv"
 	"strings"
 )
@@ -23,33 +22,44 @@ type Inject struct{}
 // assert that Inject implements CodeMod
 var _ CodeMod = Inject{}
 
-func (s Inject) Apply(source, target, match string, args ...string) error {
-	slog.Info("Applying code injector", "source", source, "target", target, "match", match, "args", args)
-
-	sourceMatches := filepath.Join(source, match)
-	matches, err := filepath.Glob(sourceMatches)
+func (s Inject) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying code injector", "match", match, "args", args)
+
+	where := args[0]
+	contents := args[1]
+	res, err := modifyMatches(ctx, files, match, func(name string, b []byte) ([]byte, int, error) {
+		log.Debug("Injecting", "file", name, "contents", contents, "at", where)
+		out, err := inject(where, contents, b)
+		return out, 1, err
+	})
 	if err != nil {
-		return fmt.Errorf("globbing source: %w", err)
-	}
-	for _, m := range matches {
-		where := args[0]
-		contents := args[1]
-		err = injectToFile(where, contents, m)
-		if err != nil {
-			return fmt.Errorf("injecting content: %w", err)
-		}
+		return res, fmt.Errorf("injecting content: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (s Inject) Validate(_, _, _ string, args ...string) error {
+func (s Inject) Validate(_ fs.FS, _ []string, args ...string) error {
 	if len(args) != 2 {
 		return errors.New("inject requires two arguments")
 	}
+	if args[0] != "start" && args[0] != "end" {
+		line, err := strconv.Atoi(args[0])
+		if err != nil || line < 1 {
+			return fmt.Errorf("invalid injection point %q, use start, end or a line number", args[0])
+		}
+	}
 	return nil
 }
 
+func (s Inject) Args() []Arg {
+	return []Arg{
+		{Name: "where", Type: ArgString, Required: true, Description: `injection point in the file: "start", "end" or a line number`},
+		{Name: "content", Type: ArgString, Required: true, Description: "content to inject"},
+	}
+}
+
 func (s Inject) Description() string {
 	return "Inject contents into a file"
 }
@@ -60,10 +70,6 @@ This codemod modifies the matched file(s) by injecting specified content.
 
 Assumes file has newlines.
 
-Args (2 required):
-	1. Injection point in the file. Valid: "start", "end", <line number>
-	2. The content to inject
-
 Example:
 	upstream: https://github.com/community-scripts/ProxmoxVE
 	modsdir: codemods
@@ -78,7 +84,6 @@ Example:
 }
 
 func inject(where, contents string, fileContent []byte) ([]byte, error) {
-	slog.Debug("Injecting", "contents", contents, "at", where)
 	var bb []byte
 
 	switch where {
@@ -115,22 +120,3 @@ func inject(where, contents string, fileContent []byte) ([]byte, error) {
 
 	return bb, nil
 }
-
-func injectToFile(where, contents, filePath string) error {
-	bb, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	modifiedContent, err := inject(where, contents, bb)
-	if err != nil {
-		return err
-	}
-
-	err = os.WriteFile(filePath, modifiedContent, 0o644)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,187 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LegacyCodeMod is the original codemod interface, whose Apply takes no
+// context and reports nothing but an error, and which is given a single
+// glob. Wrap it with Adapt to register it in Mods.
+type LegacyCodeMod interface {
+	Apply(source string, target string, match string, args ...string) error
+	Validate(source string, target string, match string, args ...string) error
+	Description() string
+	Usage() string
+}
+
+// Adapt turns a LegacyCodeMod into a CodeMod. Legacy codemods work on
+// files on disk, so the matched files are copied into a temporary
+// directory for them and the changed ones are copied back, and they are
+// given the directory of the fork, which is empty unless the fork is a
+// DirFS. The legacy codemod is applied once for each include pattern, to
+// the files it matches that no pattern before it matched; exclusions are
+// honoured by leaving the excluded files out of the temporary directory.
+// The result is worked out by comparing the files before and after Apply,
+// and the legacy codemod cannot be cancelled once it runs.
+func Adapt(m LegacyCodeMod) CodeMod {
+	return legacyMod{m}
+}
+
+type legacyMod struct {
+	LegacyCodeMod
+}
+
+func (l legacyMod) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	include, exclude, err := legacyPatterns(match)
+	if err != nil {
+		return Result{}, err
+	}
+	var res Result
+	done := map[string]bool{}
+	for _, pattern := range include {
+		err = ctx.Err()
+		if err != nil {
+			return res, err
+		}
+		err = l.apply(files, fork, append([]string{pattern}, exclude...), done, &res, args)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// apply runs the legacy codemod on the files matched by match, its first
+// pattern being the one given to the codemod, leaving out the files done
+// by earlier patterns
+func (l legacyMod) apply(files FS, fork fs.FS, match []string, done map[string]bool, res *Result, args []string) error {
+	before, err := snapshot(files, match)
+	if err != nil {
+		return err
+	}
+	for name := range before {
+		if done[name] {
+			delete(before, name)
+		}
+		done[name] = true
+	}
+	res.Matched += len(before)
+
+	scratch, err := os.MkdirTemp("", "surgeonlegacy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+	for name, bb := range before {
+		fi, err := fs.Stat(files, name)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(scratch, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(p), 0o755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(p, bb, fi.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	err = l.LegacyCodeMod.Apply(scratch, forkDir(fork), match[0], args...)
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshot(DirFS(scratch), match)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if bytes.Equal(before[name], after[name]) {
+			continue
+		}
+		if bb, ok := after[name]; ok {
+			err = files.WriteFile(name, bb, 0o644)
+		} else {
+			err = files.Remove(name)
+		}
+		if err != nil {
+			return err
+		}
+		done[name] = true
+		res.record(name, before[name], after[name], 0)
+	}
+	return nil
+}
+
+func (l legacyMod) Validate(fork fs.FS, match []string, args ...string) error {
+	include, _, err := legacyPatterns(match)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range include {
+		err = l.LegacyCodeMod.Validate("", forkDir(fork), pattern, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyPatterns returns the include patterns of match, and its exclude
+// patterns still starting with '!'
+func legacyPatterns(match []string) ([]string, []string, error) {
+	err := ValidateMatch(match)
+	if err != nil {
+		return nil, nil, err
+	}
+	var include, exclude []string
+	for _, m := range match {
+		if strings.HasPrefix(m, "!") {
+			exclude = append(exclude, m)
+		} else {
+			include = append(include, m)
+		}
+	}
+	return include, exclude, nil
+}
+
+// Args returns nil, legacy codemods only take positional arguments
+func (l legacyMod) Args() []Arg {
+	return nil
+}
+
+// snapshot reads every file in fsys matched by match, including the files
+// below matched directories
+func snapshot(fsys fs.FS, match []string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := walkMatches(fsys, match, nil, func(name string) error {
+		bb, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files[name] = bb
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
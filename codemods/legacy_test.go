@@ -0,0 +1,74 @@
+package codemods
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperMod is a codemod with the legacy interface
+type upperMod struct{}
+
+func (upperMod) Apply(source, _ string, match string, _ ...string) error {
+	matches, err := Glob(os.DirFS(source), []string{match})
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		m = filepath.Join(source, m)
+		bb, err := os.ReadFile(m)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(m, []byte("HELLO"), 0o644)
+		if err != nil || string(bb) == "HELLO" {
+			return err
+		}
+	}
+	return nil
+}
+
+func (upperMod) Validate(_, _ string, match string, _ ...string) error {
+	if match == "invalid" {
+		return errors.New("invalid match")
+	}
+	return nil
+}
+
+func (upperMod) Description() string { return "upper" }
+func (upperMod) Usage() string       { return "upper" }
+
+func TestAdapt(t *testing.T) {
+	files := MemFS{"a.txt": {Data: []byte("hello")}, "b.txt": {Data: []byte("HELLO")}}
+
+	mod := Adapt(upperMod{})
+	assert.Equal(t, "upper", mod.Description())
+	assert.NoError(t, mod.Validate(MemFS{}, []string{"*.txt"}))
+	res, err := mod.Apply(context.Background(), files, MemFS{}, []string{"*.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: 2, Changed: 1, Files: []string{"a.txt"}, Bytes: 5}, res)
+	assert.Equal(t, "HELLO", string(files["a.txt"].Data))
+}
+
+func TestAdaptPatterns(t *testing.T) {
+	files := MemFS{
+		"a.txt":        {Data: []byte("hello")},
+		"b.txt":        {Data: []byte("HELLO")},
+		"docs/c.md":    {Data: []byte("hello")},
+		"docs/skip.md": {Data: []byte("hello")},
+	}
+
+	// the legacy codemod gets one pattern at a time, each file once
+	mod := Adapt(upperMod{})
+	res, err := mod.Apply(context.Background(), files, MemFS{}, []string{"*.txt", "**/*", "!docs/skip.md"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Matched)
+	assert.Equal(t, []string{"a.txt", "docs/c.md"}, res.Files)
+	assert.Equal(t, "hello", string(files["docs/skip.md"].Data))
+	assert.EqualError(t, mod.Validate(MemFS{}, []string{"*.txt", "invalid"}), "invalid match")
+}
@@ -0,0 +1,121 @@
+package codemods
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Glob returns the sorted, slash separated paths of the files in fsys
+// matched by the match patterns. Patterns use doublestar syntax, so '**'
+// matches any number of directories, and patterns starting with '!'
+// exclude paths matched by the other patterns. Directories are never
+// returned, so 'misc/**' matches the files below misc.
+func Glob(fsys fs.FS, match []string) ([]string, error) {
+	return glob(fsys, match, doublestar.WithFilesOnly())
+}
+
+// walkMatches calls fn once for every file in fsys matched by match or
+// below a matched directory, in lexical order, for the codemods that
+// search whole directories. Exclusions apply to the files below matched
+// directories too. skip, if not nil, reports the directories below a
+// match not to descend into.
+func walkMatches(fsys fs.FS, match []string, skip func(d fs.DirEntry) bool, fn func(name string) error) error {
+	matches, err := glob(fsys, match)
+	if err != nil {
+		return err
+	}
+	_, exclude, _ := splitMatch(match)
+	seen := map[string]bool{}
+	for _, m := range matches {
+		err = fs.WalkDir(fsys, m, func(name string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case d.IsDir() && name != m && (excluded(exclude, name) || skip != nil && skip(d)):
+				return fs.SkipDir
+			case d.IsDir() || seen[name] || excluded(exclude, name):
+				return nil
+			}
+			seen[name] = true
+			return fn(name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func glob(fsys fs.FS, match []string, opts ...doublestar.GlobOption) ([]string, error) {
+	include, exclude, err := splitMatch(match)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	for _, pattern := range include {
+		matches, err := doublestar.Glob(fsys, pattern, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("globbing %s: %w", pattern, err)
+		}
+		for _, m := range matches {
+			seen[m] = struct{}{}
+		}
+	}
+
+	var paths []string
+	for m := range seen {
+		if !excluded(exclude, m) {
+			paths = append(paths, m)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ValidateMatch checks that match has at least one include pattern and
+// that every pattern is valid
+func ValidateMatch(match []string) error {
+	_, _, err := splitMatch(match)
+	return err
+}
+
+// splitMatch separates the include and exclude patterns of match
+func splitMatch(match []string) ([]string, []string, error) {
+	var include, exclude []string
+	for _, m := range match {
+		pattern, negated := strings.CutPrefix(m, "!")
+		pattern = path.Clean(filepath.ToSlash(pattern))
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, nil, fmt.Errorf("invalid match pattern %q", m)
+		}
+		if negated {
+			exclude = append(exclude, pattern)
+		} else {
+			include = append(include, pattern)
+		}
+	}
+	if len(include) == 0 {
+		return nil, nil, fmt.Errorf("match %q has no include pattern", match)
+	}
+	return include, exclude, nil
+}
+
+// excluded reports whether name, or one of its parent directories, is
+// matched by an exclude pattern
+func excluded(exclude []string, name string) bool {
+	for _, pattern := range exclude {
+		for p := name; p != "."; p = path.Dir(p) {
+			if ok, _ := doublestar.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
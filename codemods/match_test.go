@@ -0,0 +1,92 @@
+package codemods
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlob(t *testing.T) {
+	files := MemFS{}
+	for _, f := range []string{
+		"install.sh",
+		"misc/build.func",
+		"misc/tools.func",
+		"ct/app.sh",
+		"ct/sub/deep.sh",
+		"ct/vendor/lib.sh",
+		"vendor/mod/lib.sh",
+	} {
+		files[f] = &MemFile{Data: []byte(f)}
+	}
+
+	tests := []struct {
+		name     string
+		match    []string
+		expected []string
+	}{
+		{
+			name:     "Single directory glob",
+			match:    []string{"misc/*.func"},
+			expected: []string{"misc/build.func", "misc/tools.func"},
+		},
+		{
+			name:     "Recursive glob",
+			match:    []string{"ct/**/*.sh"},
+			expected: []string{"ct/app.sh", "ct/sub/deep.sh", "ct/vendor/lib.sh"},
+		},
+		{
+			name:     "Multiple patterns",
+			match:    []string{"install.sh", "misc/build.func", "install.sh"},
+			expected: []string{"install.sh", "misc/build.func"},
+		},
+		{
+			name:     "Exclusions",
+			match:    []string{"**/*.sh", "!**/vendor/**", "!ct/sub"},
+			expected: []string{"ct/app.sh", "install.sh"},
+		},
+		{
+			name:     "Directory contents",
+			match:    []string{"misc/**"},
+			expected: []string{"misc/build.func", "misc/tools.func"},
+		},
+		{
+			name:     "No matches",
+			match:    []string{"docs/*.md"},
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := Glob(files, tt.match)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matches)
+		})
+	}
+}
+
+func TestWalkMatches(t *testing.T) {
+	files := MemFS{
+		"go.mod":              {Data: []byte("module x")},
+		"cmd/main.go":         {Data: []byte("package main")},
+		"cmd/testdata/x.go":   {Data: []byte("package x")},
+		"internal/gen/gen.go": {Data: []byte("package gen")},
+	}
+	var names []string
+	skip := func(d fs.DirEntry) bool { return d.Name() == "testdata" }
+	err := walkMatches(files, []string{"*", "cmd/main.go", "!internal/gen"}, skip, func(name string) error {
+		names = append(names, name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd/main.go", "go.mod"}, names)
+}
+
+func TestValidateMatch(t *testing.T) {
+	assert.NoError(t, ValidateMatch([]string{"**/*.sh", "!vendor/**"}))
+	assert.Error(t, ValidateMatch([]string{"!vendor/**"}))
+	assert.Error(t, ValidateMatch(nil))
+	assert.Error(t, ValidateMatch([]string{"misc/[a.func"}))
+}
@@ -0,0 +1,438 @@
+package codemods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Mods["patch"] = Patch{}
+}
+
+type Patch struct{}
+
+// assert that Patch implements CodeMod
+var _ CodeMod = Patch{}
+
+// defaultPatchFuzz matches the default fuzz factor of GNU patch
+const defaultPatchFuzz = 2
+
+func (s Patch) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying patch", "match", match, "args", args)
+
+	patches, fuzz, maxOffset, err := parsePatchArgs(fork, args...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	applied := make([]bool, len(patches))
+	res, err := modifyMatches(ctx, files, match, func(rel string, b []byte) ([]byte, int, error) {
+		var hunks int
+		for i, fp := range patches {
+			// a patch for a single file applies to every matched file
+			if len(patches) > 1 && fp.path() != rel {
+				continue
+			}
+			var err error
+			b, err = applyHunks(log, fp.hunks, fuzz, maxOffset, b)
+			if err != nil {
+				return nil, 0, fmt.Errorf("applying %s to %s: %w", args[0], rel, err)
+			}
+			applied[i] = true
+			hunks += len(fp.hunks)
+		}
+		return b, hunks, nil
+	})
+	if err != nil {
+		return res, err
+	}
+	for i, fp := range patches {
+		if !applied[i] {
+			return res, fmt.Errorf("applying %s: no matched file for %s", args[0], fp.path())
+		}
+	}
+
+	return res, nil
+}
+
+func (s Patch) Validate(fork fs.FS, _ []string, args ...string) error {
+	if len(args) < 1 || len(args) > 3 {
+		return errors.New("patch requires one to three arguments")
+	}
+	_, _, _, err := parsePatchArgs(fork, args...)
+	return err
+}
+
+func (s Patch) Args() []Arg {
+	return []Arg{
+		{Name: "patch", Type: ArgPath, Required: true, Description: "patch file in your fork"},
+		{Name: "fuzz", Type: ArgInt, Default: "2", Description: "number of context lines that may be ignored"},
+		{Name: "max_offset", Type: ArgInt, Description: "maximum offset in lines from the recorded position, unlimited if empty"},
+	}
+}
+
+func (s Patch) Description() string {
+	return "Apply a unified diff to a file"
+}
+
+func (s Patch) Usage() string {
+	return `Apply a unified diff to a file.
+This codemod applies the hunks of a unified diff (as produced by
+"git diff" or "diff -u") from your fork to the matched file(s).
+
+Like GNU patch, hunks are located at their recorded line numbers, or
+at an offset from them when upstream has added or removed lines
+elsewhere in the file. If a hunk still does not apply, up to "fuzz"
+lines of leading and trailing context are ignored.
+
+If the patch touches a single file it is applied to every matched
+file. Otherwise each file in the patch is applied to the matched file
+with the same path, and every file in the patch must be matched.
+Patches can only modify existing files.
+
+When a hunk cannot be applied the codemod fails and lists every
+failed hunk; the file is left unchanged.
+
+Example:
+	upstream: https://github.com/community-scripts/ProxmoxVE
+	modsdir: codemods
+	codemods:
+	- description: Storage selection fixes
+		mod: patch
+		match: misc/build.func
+		args:
+		- codemods/build.func.patch
+		- 1
+	`
+}
+
+func parsePatchArgs(fork fs.FS, args ...string) ([]*filePatch, int, int, error) {
+	fuzz := defaultPatchFuzz
+	maxOffset := -1
+	var err error
+	if len(args) > 1 && args[1] != "" {
+		fuzz, err = strconv.Atoi(args[1])
+		if err != nil || fuzz < 0 {
+			return nil, 0, 0, fmt.Errorf("invalid fuzz %q", args[1])
+		}
+	}
+	if len(args) > 2 && args[2] != "" {
+		maxOffset, err = strconv.Atoi(args[2])
+		if err != nil || maxOffset < 0 {
+			return nil, 0, 0, fmt.Errorf("invalid offset %q", args[2])
+		}
+	}
+
+	bb, err := readForkFile(fork, args[0])
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("reading patch: %w", err)
+	}
+	files, err := parsePatch(bb)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("parsing %s: %w", args[0], err)
+	}
+	return files, fuzz, maxOffset, nil
+}
+
+// filePatch holds the hunks of a unified diff for one file
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []*patchHunk
+}
+
+// path returns the path of the patched file without its a/ or b/ prefix
+func (fp *filePatch) path() string {
+	p := fp.newPath
+	if p == "/dev/null" {
+		p = fp.oldPath
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return p
+}
+
+// patchHunk is a single @@ section of a unified diff
+type patchHunk struct {
+	number   int
+	header   string
+	oldStart int
+	oldLines int
+	newStart int
+	newLines int
+	lines    []string // each line keeps its ' ', '-' or '+' prefix
+	oldNoEOL bool
+	newNoEOL bool
+}
+
+// before returns the lines the hunk expects to find
+func (h *patchHunk) before() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l[0] != '+' {
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+// after returns the lines the hunk leaves behind
+func (h *patchHunk) after() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l[0] != '-' {
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+// markNoEOL records a "\ No newline at end of file" marker, which
+// refers to the line before it
+func (h *patchHunk) markNoEOL() {
+	if len(h.lines) == 0 {
+		return
+	}
+	switch h.lines[len(h.lines)-1][0] {
+	case '-':
+		h.oldNoEOL = true
+	case '+':
+		h.newNoEOL = true
+	default:
+		h.oldNoEOL = true
+		h.newNoEOL = true
+	}
+}
+
+// context returns the number of leading and trailing context lines
+func (h *patchHunk) context() (int, int) {
+	var top, bottom int
+	for top < len(h.lines) && h.lines[top][0] == ' ' {
+		top++
+	}
+	for bottom < len(h.lines)-top && h.lines[len(h.lines)-1-bottom][0] == ' ' {
+		bottom++
+	}
+	return top, bottom
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch parses a unified diff into per-file patches
+func parsePatch(content []byte) ([]*filePatch, error) {
+	lines := strings.Split(string(content), "\n")
+	var files []*filePatch
+	var cur *filePatch
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			cur = &filePatch{
+				oldPath: patchPath(line[4:]),
+				newPath: patchPath(lines[i+1][4:]),
+			}
+			files = append(files, cur)
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			h := &patchHunk{
+				number:   len(cur.hunks) + 1,
+				header:   line,
+				oldStart: atoiDefault(m[1], 1),
+				oldLines: atoiDefault(m[2], 1),
+				newStart: atoiDefault(m[3], 1),
+				newLines: atoiDefault(m[4], 1),
+			}
+			var oldSeen, newSeen int
+			for oldSeen < h.oldLines || newSeen < h.newLines {
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("hunk #%d of %s: unexpected end of patch", h.number, cur.path())
+				}
+				l := lines[i]
+				if l == "" {
+					// some editors strip the space from empty context lines
+					l = " "
+				}
+				switch l[0] {
+				case ' ':
+					oldSeen++
+					newSeen++
+				case '-':
+					oldSeen++
+				case '+':
+					newSeen++
+				case '\\':
+					h.markNoEOL()
+					continue
+				default:
+					return nil, fmt.Errorf("line %d: unexpected %q in hunk #%d", i+1, l, h.number)
+				}
+				h.lines = append(h.lines, l)
+			}
+			if oldSeen != h.oldLines || newSeen != h.newLines {
+				return nil, fmt.Errorf("hunk #%d of %s: line counts do not match header", h.number, cur.path())
+			}
+			// a trailing "\ No newline at end of file" refers to the last line
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+				i++
+				h.markNoEOL()
+			}
+			cur.hunks = append(cur.hunks, h)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no file patches found")
+	}
+	for _, fp := range files {
+		if fp.oldPath == "/dev/null" || fp.newPath == "/dev/null" {
+			return nil, fmt.Errorf("%s: creating or deleting files is not supported", fp.path())
+		}
+		if len(fp.hunks) == 0 {
+			return nil, fmt.Errorf("%s: no hunks found", fp.path())
+		}
+	}
+	return files, nil
+}
+
+// patchPath strips the timestamp that diff -u appends to file names
+func patchPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// applyHunks applies the hunks to fileContent. Each hunk is searched for
+// at its recorded position adjusted by the offset of the previous hunk,
+// then up to maxOffset lines (unlimited if negative) either side, and then
+// again with up to fuzz context lines removed from each end. All hunks that
+// fail to apply are reported in the returned error.
+func applyHunks(log *slog.Logger, hunks []*patchHunk, fuzz, maxOffset int, fileContent []byte) ([]byte, error) {
+	text := string(fileContent)
+	trailingNL := strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+	var lines []string
+	if text != "" || trailingNL {
+		lines = strings.Split(text, "\n")
+	}
+
+	var out []string
+	var failed []error
+	last, offset := 0, 0
+	for _, h := range hunks {
+		pos, top, bottom, ok := locateHunk(h, lines, last, offset, fuzz, maxOffset)
+		if !ok {
+			failed = append(failed, fmt.Errorf("hunk #%d FAILED at %d: %s", h.number, h.oldStart, h.header))
+			continue
+		}
+		old, repl := h.before(), h.after()
+		old, repl = old[top:len(old)-bottom], repl[top:len(repl)-bottom]
+		expected := hunkStart(h) + top
+		if pos != expected+offset || top > 0 || bottom > 0 {
+			log.Debug("Hunk applied", "hunk", h.number, "offset", pos-expected, "fuzz", max(top, bottom))
+		}
+		offset = pos - expected
+
+		out = append(out, lines[last:pos]...)
+		out = append(out, repl...)
+		last = pos + len(old)
+		if last == len(lines) {
+			if h.newNoEOL {
+				trailingNL = false
+			} else if h.oldNoEOL {
+				trailingNL = true
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return nil, errors.Join(failed...)
+	}
+	out = append(out, lines[last:]...)
+
+	result := strings.Join(out, "\n")
+	if trailingNL && len(out) > 0 {
+		result += "\n"
+	}
+	return []byte(result), nil
+}
+
+// hunkStart returns the zero-based line at which the hunk's old lines begin
+func hunkStart(h *patchHunk) int {
+	if h.oldLines == 0 {
+		// an insertion records the line it follows
+		return h.oldStart
+	}
+	return h.oldStart - 1
+}
+
+// locateHunk finds where the hunk applies, returning the line position and
+// the number of leading and trailing context lines that were ignored.
+func locateHunk(h *patchHunk, lines []string, from, offset, fuzz, maxOffset int) (int, int, int, bool) {
+	old := h.before()
+	top, bottom := h.context()
+	for f := 0; f <= fuzz; f++ {
+		t, b := min(f, top), min(f, bottom)
+		if f > 0 && t == 0 && b == 0 {
+			break
+		}
+		want := old[t : len(old)-b]
+		if len(want) == 0 && len(old) > 0 {
+			// without any context left the hunk would apply anywhere
+			break
+		}
+		expected := hunkStart(h) + t + offset
+		limit := maxOffset
+		if limit < 0 {
+			limit = len(lines)
+		}
+		for d := 0; d <= limit; d++ {
+			for _, pos := range []int{expected + d, expected - d} {
+				if pos < from || pos+len(want) > len(lines) {
+					continue
+				}
+				if linesEqual(lines[pos:pos+len(want)], want) {
+					return pos, t, b, true
+				}
+				if d == 0 {
+					break
+				}
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+func linesEqual(a, b []string) bool {
+	for i := range b {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
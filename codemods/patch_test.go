@@ -0,0 +1,126 @@
+package codemods
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPatch = `diff --git a/misc/build.func b/misc/build.func
+--- a/misc/build.func
++++ b/misc/build.func
+@@ -2,3 +2,3 @@
+ two
+-three
++THREE
+ four
+@@ -7,3 +7,4 @@
+ seven
+ eight
++eight and a half
+ nine
+`
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       string
+		fuzz        int
+		maxOffset   int
+		fileContent string
+		expected    string
+		expectError string
+	}{
+		{
+			name:        "Apply at recorded position",
+			patch:       testPatch,
+			maxOffset:   -1,
+			fileContent: "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			expected:    "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\neight and a half\nnine\nten\n",
+		},
+		{
+			name:        "Apply with offset",
+			patch:       testPatch,
+			maxOffset:   -1,
+			fileContent: "zero\nzero\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			expected:    "zero\nzero\none\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\neight and a half\nnine\nten\n",
+		},
+		{
+			name:        "Offset exceeds maximum",
+			patch:       testPatch,
+			maxOffset:   1,
+			fileContent: "zero\nzero\none\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			expectError: "hunk #1 FAILED at 2: @@ -2,3 +2,3 @@\nhunk #2 FAILED at 7: @@ -7,3 +7,4 @@",
+		},
+		{
+			name:        "Apply with fuzz",
+			patch:       testPatch,
+			fuzz:        1,
+			maxOffset:   -1,
+			fileContent: "one\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			expected:    "one\nTWO\nTHREE\nfour\nfive\nsix\nseven\neight\neight and a half\nnine\nten\n",
+		},
+		{
+			name:        "Context changed without fuzz",
+			patch:       testPatch,
+			maxOffset:   -1,
+			fileContent: "one\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			expectError: "hunk #1 FAILED at 2: @@ -2,3 +2,3 @@",
+		},
+		{
+			name:        "Report every failed hunk",
+			patch:       testPatch,
+			fuzz:        1,
+			maxOffset:   -1,
+			fileContent: "one\ntwo\n3\nfour\nfive\nsix\nseven\n8\nnine\nten\n",
+			expectError: "hunk #1 FAILED at 2: @@ -2,3 +2,3 @@\nhunk #2 FAILED at 7: @@ -7,3 +7,4 @@",
+		},
+		{
+			name: "Add missing newline",
+			patch: `--- a/f
++++ b/f
+@@ -1 +1 @@
+-one
+\ No newline at end of file
++one
+`,
+			maxOffset:   -1,
+			fileContent: "one",
+			expected:    "one\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parsePatch([]byte(tt.patch))
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+			result, err := applyHunks(slog.Default(), files[0].hunks, tt.fuzz, tt.maxOffset, []byte(tt.fileContent))
+			if tt.expectError != "" {
+				assert.EqualError(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	files, err := parsePatch([]byte(testPatch))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "misc/build.func", files[0].path())
+	assert.Len(t, files[0].hunks, 2)
+
+	_, err = parsePatch([]byte("--- /dev/null\n+++ b/new\n@@ -0,0 +1 @@\n+new\n"))
+	assert.Error(t, err)
+
+	_, err = parsePatch([]byte("--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-one\n"))
+	assert.Error(t, err)
+
+	_, err = parsePatch([]byte("not a patch"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PluginPrefix starts the name of every plugin codemod executable, the
+// rest of the name is the name of the codemod
+const PluginPrefix = "surgeon-mod-"
+
+// PluginProtocol is the version of the plugin protocol. Version 2 encodes
+// file content in base64.
+const PluginProtocol = 2
+
+// plugin actions
+const (
+	PluginDescribe = "describe"
+	PluginValidate = "validate"
+	PluginApply    = "apply"
+)
+
+// PluginRequest is written as JSON to the standard input of a plugin, which
+// handles one request per run
+type PluginRequest struct {
+	Protocol int          `json:"protocol"`
+	Action   string       `json:"action"`          // describe, validate or apply
+	Target   string       `json:"target"`          // root of the fork on disk, for files the arguments refer to
+	Args     []string     `json:"args"`            // positional arguments, named ones resolved
+	Files    []PluginFile `json:"files,omitempty"` // matched files to apply the codemod to
+}
+
+// PluginFile is a file passed to or returned by a plugin
+type PluginFile struct {
+	Path        string `json:"path"`                  // relative to the upstream tree, with forward slashes
+	Content     []byte `json:"content"`               // base64 in JSON, files need not be UTF-8
+	Occurrences int    `json:"occurrences,omitempty"` // replacements or insertions made
+}
+
+// PluginResponse is read as JSON from the standard output of a plugin
+type PluginResponse struct {
+	Description string       `json:"description,omitempty"` // describe
+	Usage       string       `json:"usage,omitempty"`       // describe
+	Args        []Arg        `json:"args,omitempty"`        // describe, nil for positional arguments only
+	Files       []PluginFile `json:"files,omitempty"`       // apply, the changed files with their new content
+	Warnings    []string     `json:"warnings,omitempty"`    // apply, diagnostics that did not stop the codemod
+	Error       string       `json:"error,omitempty"`       // validate and apply, the arguments or files are invalid
+}
+
+// Plugin is a codemod implemented by an external executable that speaks
+// the plugin protocol: it reads a PluginRequest from standard input and
+// writes a PluginResponse to standard output. A non-zero exit status fails
+// the request, with standard error as the message.
+type Plugin struct {
+	Name string
+	Path string
+
+	once     sync.Once
+	describe PluginResponse
+}
+
+// assert that Plugin implements CodeMod
+var _ CodeMod = &Plugin{}
+
+// FindPlugins returns the plugin codemods in dirs, by name. When several
+// dirs hold a plugin with the same name the first one wins.
+func FindPlugins(dirs ...string) map[string]*Plugin {
+	plugins := map[string]*Plugin{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), PluginPrefix)
+			name = strings.TrimSuffix(name, ".exe")
+			if !ok || name == "" || plugins[name] != nil {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			fi, err := os.Stat(path)
+			if err != nil || fi.IsDir() || fi.Mode()&0o111 == 0 {
+				continue
+			}
+			plugins[name] = &Plugin{Name: name, Path: path}
+		}
+	}
+	return plugins
+}
+
+// RegisterPlugins adds the plugin codemods found in dirs to Mods. Built in
+// codemods take precedence over plugins with the same name.
+func RegisterPlugins(dirs ...string) {
+	for name, p := range FindPlugins(dirs...) {
+		if _, ok := Mods[name]; ok {
+			slog.Debug("Plugin shadowed by another codemod", "name", name, "path", p.Path)
+			continue
+		}
+		slog.Debug("Registering plugin codemod", "name", name, "path", p.Path)
+		Mods[name] = p
+	}
+}
+
+// run sends req to the plugin and returns its response
+func (p *Plugin) run(ctx context.Context, req PluginRequest) (PluginResponse, error) {
+	req.Protocol = PluginProtocol
+	in, err := json.Marshal(req)
+	if err != nil {
+		return PluginResponse{}, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return PluginResponse{}, fmt.Errorf("running plugin %s %s: %w", p.Name, req.Action, err)
+	}
+	var res PluginResponse
+	err = json.Unmarshal(stdout.Bytes(), &res)
+	if err != nil {
+		return res, fmt.Errorf("reading plugin %s %s response: %w", p.Name, req.Action, err)
+	}
+	if res.Error != "" {
+		return res, errors.New(res.Error)
+	}
+	return res, nil
+}
+
+// described returns the response of the plugin to describe, which is only
+// requested once
+func (p *Plugin) described() PluginResponse {
+	p.once.Do(func() {
+		res, err := p.run(context.Background(), PluginRequest{Action: PluginDescribe})
+		if err != nil {
+			slog.Warn("Describing plugin codemod", "name", p.Name, "path", p.Path, "error", err)
+			res = PluginResponse{Description: fmt.Sprintf("plugin %s cannot describe itself: %v", p.Path, err)}
+		}
+		p.describe = res
+	})
+	return p.describe
+}
+
+func (p *Plugin) Description() string {
+	return p.described().Description
+}
+
+func (p *Plugin) Usage() string {
+	return p.described().Usage
+}
+
+func (p *Plugin) Args() []Arg {
+	return p.described().Args
+}
+
+func (p *Plugin) Validate(fork fs.FS, _ []string, args ...string) error {
+	_, err := p.run(context.Background(), PluginRequest{Action: PluginValidate, Target: forkDir(fork), Args: args})
+	return err
+}
+
+func (p *Plugin) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	Logger(ctx).Info("Applying plugin", "name", p.Name, "match", match, "args", args)
+
+	matches, err := Glob(files, match)
+	if err != nil {
+		return Result{}, fmt.Errorf("globbing source: %w", err)
+	}
+	req := PluginRequest{Action: PluginApply, Target: forkDir(fork), Args: args}
+	for _, m := range matches {
+		bb, err := fs.ReadFile(files, m)
+		if err != nil {
+			return Result{}, err
+		}
+		req.Files = append(req.Files, PluginFile{Path: m, Content: bb})
+	}
+
+	res := Result{Matched: len(req.Files)}
+	out, err := p.run(ctx, req)
+	res.Warnings = out.Warnings
+	if err != nil {
+		return res, fmt.Errorf("applying plugin %s: %w", p.Name, err)
+	}
+	for _, f := range out.Files {
+		// plugins may only change the files they were given
+		if !slices.ContainsFunc(req.Files, func(in PluginFile) bool { return in.Path == f.Path }) {
+			return res, fmt.Errorf("applying plugin %s: %s was not one of the matched files", p.Name, f.Path)
+		}
+		err = res.rewriteFile(files, f.Path, func([]byte) ([]byte, int, error) {
+			return f.Content, f.Occurrences, nil
+		})
+		if err != nil {
+			return res, fmt.Errorf("applying plugin %s: %w", p.Name, err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a plugin codemod that upper cases
+// files when started by the plugin tests
+func TestMain(m *testing.M) {
+	if os.Getenv("SURGEON_TEST_PLUGIN") == "1" {
+		servePlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func servePlugin() {
+	var req PluginRequest
+	err := json.NewDecoder(os.Stdin).Decode(&req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var res PluginResponse
+	switch req.Action {
+	case PluginDescribe:
+		res.Description = "Upper cases text"
+		res.Usage = "upper <word>"
+		res.Args = []Arg{{Name: "word", Type: ArgString, Required: true, Description: "word to upper case"}}
+	case PluginValidate:
+		if len(req.Args) != 1 {
+			res.Error = "upper requires one argument"
+		}
+	case PluginApply:
+		for _, f := range req.Files {
+			n := bytes.Count(f.Content, []byte(req.Args[0]))
+			if n == 0 {
+				res.Warnings = append(res.Warnings, f.Path+" does not contain "+req.Args[0])
+				continue
+			}
+			if req.Args[0] == "escape" {
+				f.Path = "../" + f.Path
+			}
+			f.Content = bytes.ReplaceAll(f.Content, []byte(req.Args[0]), []byte(strings.ToUpper(req.Args[0])))
+			f.Occurrences = n
+			res.Files = append(res.Files, f)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown action", req.Action)
+		os.Exit(2)
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(res)
+}
+
+func TestPlugin(t *testing.T) {
+	t.Setenv("SURGEON_TEST_PLUGIN", "1")
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	bin := t.TempDir()
+	require.NoError(t, os.Symlink(exe, filepath.Join(bin, PluginPrefix+"upper")))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, PluginPrefix+"notexecutable"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "other"), nil, 0o755))
+
+	plugins := FindPlugins(filepath.Join(bin, "missing"), bin)
+	require.Len(t, plugins, 1)
+	p := plugins["upper"]
+	require.NotNil(t, p)
+
+	assert.Equal(t, "Upper cases text", p.Description())
+	assert.Equal(t, "upper <word>", p.Usage())
+	assert.Equal(t, "word", p.Args()[0].Name)
+
+	assert.NoError(t, p.Validate(MemFS{}, nil, "hello"))
+	assert.EqualError(t, p.Validate(MemFS{}, nil), "upper requires one argument")
+
+	source := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("hello hello world"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "b.txt"), []byte("goodbye"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "c.bin"), []byte("hello\xff\xfe"), 0o644))
+	tree := NewTree(DirFS(source))
+	res, err := p.Apply(context.Background(), tree, MemFS{}, []string{"*.txt", "*.bin"}, "hello")
+	require.NoError(t, err)
+	require.NoError(t, tree.Flush())
+	assert.Equal(t, 3, res.Matched)
+	assert.Equal(t, 2, res.Changed)
+	assert.Equal(t, 3, res.Occurrences)
+	assert.Equal(t, []string{"a.txt", "c.bin"}, res.Files)
+	assert.Equal(t, []string{"b.txt does not contain hello"}, res.Warnings)
+	bb, err := os.ReadFile(filepath.Join(source, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO HELLO world", string(bb))
+	bb, err = os.ReadFile(filepath.Join(source, "c.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("HELLO\xff\xfe"), bb, "content that is not UTF-8 is kept")
+	fi, err := os.Stat(filepath.Join(source, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	require.NoError(t, os.WriteFile(filepath.Join(source, "c.txt"), []byte("escape"), 0o644))
+	_, err = p.Apply(context.Background(), DirFS(source), MemFS{}, []string{"c.txt"}, "escape")
+	assert.ErrorContains(t, err, "was not one of the matched files")
+}
@@ -0,0 +1,145 @@
+package codemods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Mods["regex"] = Regex{}
+}
+
+type Regex struct{}
+
+// assert that Regex implements CodeMod
+var _ CodeMod = Regex{}
+
+func (s Regex) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying regex", "match", match, "args", args)
+
+	re, limit, err := parseRegexArgs(args...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res, err := modifyMatches(ctx, files, match, func(_ string, b []byte) ([]byte, int, error) {
+		out, n := regexReplace(re, args[1], limit, b)
+		return out, n, nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("applying regex: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s Regex) Validate(_ fs.FS, _ []string, args ...string) error {
+	if len(args) < 2 || len(args) > 4 {
+		return errors.New("regex requires two to four arguments")
+	}
+	_, _, err := parseRegexArgs(args...)
+	return err
+}
+
+func (s Regex) Args() []Arg {
+	return []Arg{
+		{Name: "pattern", Type: ArgString, Required: true, Description: "regular expression (https://pkg.go.dev/regexp/syntax)"},
+		{Name: "replacement", Type: ArgString, Required: true, Description: "replacement, $1 or ${name} expand capture groups and $$ is a dollar sign"},
+		{Name: "flags", Type: ArgString, Description: "any of i (case-insensitive), m (^ and $ match at line boundaries) and s (. matches newlines)"},
+		{Name: "limit", Type: ArgInt, Default: "0", Description: "maximum number of replacements per file, 0 for all"},
+	}
+}
+
+func (s Regex) Description() string {
+	return "Replace regular expression matches in a file"
+}
+
+func (s Regex) Usage() string {
+	return `Replace regular expression matches in a file.
+This codemod replaces matches of a Go RE2 regular expression in the
+matched file(s). The replacement may reference capture groups with
+$1 or ${name}; use $$ for a literal dollar sign.
+
+Example:
+	upstream: https://github.com/community-scripts/ProxmoxVE
+	modsdir: codemods
+	codemods:
+	- description: Raw URL Updates
+		mod: regex
+		match: misc/*.func
+		args:
+		- https://github.com/community-scripts/ProxmoxVE/raw/(main|develop)/
+		- https://github.com/bketelsen/IncusScripts/raw/$1/
+		- i
+	`
+}
+
+// parseRegexArgs compiles the expression in args[0] with the flags in
+// args[2] and parses the replacement limit in args[3].
+func parseRegexArgs(args ...string) (*regexp.Regexp, int, error) {
+	if len(args) < 2 {
+		return nil, 0, errors.New("regex requires a pattern and a replacement")
+	}
+	var flags string
+	if len(args) > 2 {
+		flags = args[2]
+	}
+	re, err := compileRegex(args[0], flags)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := -1
+	if len(args) > 3 && args[3] != "" {
+		limit, err = strconv.Atoi(args[3])
+		if err != nil || limit < 0 {
+			return nil, 0, fmt.Errorf("invalid replacement limit %q", args[3])
+		}
+		if limit == 0 {
+			limit = -1
+		}
+	}
+	return re, limit, nil
+}
+
+// compileRegex compiles pattern with the given flag letters
+func compileRegex(pattern, flags string) (*regexp.Regexp, error) {
+	for _, f := range flags {
+		if !strings.ContainsRune("ims", f) {
+			return nil, fmt.Errorf("unknown regex flag %q", f)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compiling regex: %w", err)
+	}
+	return re, nil
+}
+
+// regexReplace replaces up to limit matches of re in fileContent with
+// the expanded template and returns the number of replacements. A negative
+// limit replaces all matches.
+func regexReplace(re *regexp.Regexp, template string, limit int, fileContent []byte) ([]byte, int) {
+	matches := re.FindAllSubmatchIndex(fileContent, limit)
+	if len(matches) == 0 {
+		return fileContent, 0
+	}
+
+	var result []byte
+	last := 0
+	for _, m := range matches {
+		result = append(result, fileContent[last:m[0]]...)
+		result = re.Expand(result, []byte(template), fileContent, m)
+		last = m[1]
+	}
+	return append(result, fileContent[last:]...), len(matches)
+}
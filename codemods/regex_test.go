@@ -0,0 +1,95 @@
+package codemods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexReplace(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		fileContent []byte
+		expected    []byte
+		expectError bool
+	}{
+		{
+			name:        "Replace all occurrences",
+			args:        []string{"fo+", "bar"},
+			fileContent: []byte("foo fooo fo"),
+			expected:    []byte("bar bar bar"),
+		},
+		{
+			name:        "Numbered capture group",
+			args:        []string{`raw/(main|develop)/`, "fork/raw/$1/"},
+			fileContent: []byte("x raw/main/a raw/develop/b"),
+			expected:    []byte("x fork/raw/main/a fork/raw/develop/b"),
+		},
+		{
+			name:        "Named capture group",
+			args:        []string{`(?P<key>\w+)=(?P<value>\w+)`, "${value}=${key}"},
+			fileContent: []byte("a=b c=d"),
+			expected:    []byte("b=a d=c"),
+		},
+		{
+			name:        "Case-insensitive",
+			args:        []string{"foo", "bar", "i"},
+			fileContent: []byte("FOO Foo foo"),
+			expected:    []byte("bar bar bar"),
+		},
+		{
+			name:        "Multi-line anchors",
+			args:        []string{"^#", "//", "m"},
+			fileContent: []byte("# one\n# two\nthree # four"),
+			expected:    []byte("// one\n// two\nthree # four"),
+		},
+		{
+			name:        "Limit occurrences",
+			args:        []string{"foo", "bar", "", "2"},
+			fileContent: []byte("foo foo foo"),
+			expected:    []byte("bar bar foo"),
+		},
+		{
+			name:        "Zero limit replaces all",
+			args:        []string{"foo", "bar", "", "0"},
+			fileContent: []byte("foo foo foo"),
+			expected:    []byte("bar bar bar"),
+		},
+		{
+			name:        "No occurrences",
+			args:        []string{"foo", "bar"},
+			fileContent: []byte("baz qux"),
+			expected:    []byte("baz qux"),
+		},
+		{
+			name:        "Invalid pattern",
+			args:        []string{"(foo", "bar"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown flag",
+			args:        []string{"foo", "bar", "x"},
+			expectError: true,
+		},
+		{
+			name:        "Invalid limit",
+			args:        []string{"foo", "bar", "", "many"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, limit, err := parseRegexArgs(tt.args...)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			result, _ := regexReplace(re, tt.args[1], limit, tt.fileContent)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -1,10 +1,11 @@
 package codemods
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
 )
 
@@ -17,32 +18,45 @@ type ReplaceFile struct{}
 // assert that Sed implements CodeMod
 var _ CodeMod = ReplaceFile{}
 
-func (s ReplaceFile) Apply(source, target, match string, args ...string) error {
-	slog.Info("Applying replacefile", "source", source, "target", target, "match", match, "args", args)
+func (s ReplaceFile) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying replacefile", "match", match, "args", args)
 
-	sourceMatches := filepath.Join(source, match)
-	matches, err := filepath.Glob(sourceMatches)
+	bb, err := readForkFile(fork, args[0])
 	if err != nil {
-		return fmt.Errorf("globbing source: %w", err)
+		return Result{}, fmt.Errorf("applying replacement: %w", err)
 	}
-	for _, m := range matches {
-		replacementPath := filepath.Join(target, args[0])
-		err = replace(replacementPath, m)
-		if err != nil {
-			return fmt.Errorf("applying replacement: %w", err)
-		}
+	res, err := modifyMatches(ctx, files, match, func(path string, _ []byte) ([]byte, int, error) {
+		log.Debug("Replacing", "file", path, "with", args[0])
+		return bb, 1, nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("applying replacement: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (s ReplaceFile) Validate(_, _, _ string, args ...string) error {
+func (s ReplaceFile) Validate(fork fs.FS, _ []string, args ...string) error {
 	if len(args) != 1 {
-		return errors.New("replacefile requires two arguments")
+		return errors.New("replacefile requires one argument")
+	}
+	fi, err := fs.Stat(fork, path.Clean(filepath.ToSlash(args[0])))
+	if err != nil {
+		return fmt.Errorf("replacement file: %w", err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("replacement file %s is a directory", args[0])
 	}
 	return nil
 }
 
+func (s ReplaceFile) Args() []Arg {
+	return []Arg{
+		{Name: "replacement", Type: ArgPath, Required: true, Description: "file in your fork replacing the matched file(s)"},
+	}
+}
+
 func (s ReplaceFile) Description() string {
 	return "Replace a file with another"
 }
@@ -51,9 +65,6 @@ func (s ReplaceFile) Usage() string {
 	return `Replace a file with another.
 This codemod replaces the matched file(s) with a file from your fork.
 
-Args (1 required):
-	1. The path to the file (in your fork) to replace the matched file(s)
-
 Example:
 	upstream: https://github.com/community-scripts/ProxmoxVE
 	modsdir: codemods
@@ -65,16 +76,3 @@ Example:
 		- codemods/create_lxc.sh
 	`
 }
-
-func replace(newfile, oldfile string) error {
-	slog.Debug("Replacing", "file", oldfile, "with", newfile)
-	bb, err := os.ReadFile(newfile)
-	if err != nil {
-		return err
-	}
-	err = os.WriteFile(oldfile, bb, 0o644)
-	if err != nil {
-		return err
-	}
-	return nil
-}
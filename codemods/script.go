@@ -0,0 +1,181 @@
+package codemods
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+func init() {
+	Mods["script"] = Script{}
+}
+
+// scriptMaxSteps bounds the work a script may do per file, so a runaway
+// loop fails instead of hanging the sync
+const scriptMaxSteps = 100_000_000
+
+type Script struct{}
+
+// assert that Script implements CodeMod
+var _ CodeMod = Script{}
+
+func (s Script) Apply(ctx context.Context, files FS, fork fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying script", "match", match, "args", args)
+
+	thread, modify, err := loadScript(log, fork, args...)
+	if err != nil {
+		return Result{}, fmt.Errorf("applying script: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+
+	res, err := modifyMatches(ctx, files, match, func(name string, b []byte) ([]byte, int, error) {
+		return runScript(thread, modify, name, b)
+	})
+	if err != nil {
+		return res, fmt.Errorf("applying script: %w", err)
+	}
+	return res, nil
+}
+
+func (s Script) Validate(fork fs.FS, _ []string, args ...string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("script requires one or two arguments")
+	}
+	_, _, err := loadScript(slog.Default(), fork, args...)
+	return err
+}
+
+func (s Script) Args() []Arg {
+	return []Arg{
+		{Name: "script", Type: ArgPath, Required: true, Description: "Starlark script in your fork defining modify(path, content)"},
+		{Name: "arg", Type: ArgString, Description: "value passed to the script as the global arg"},
+	}
+}
+
+func (s Script) Description() string {
+	return "Modify files with a Starlark script"
+}
+
+func (s Script) Usage() string {
+	return `Modify files with a Starlark script.
+This codemod runs a Starlark (https://github.com/bazelbuild/starlark)
+script from your fork for every matched file. The script defines a
+modify function taking the path of the file, relative to the root of the
+repository, and its content. It returns the new content, None to leave
+the file unchanged, or a tuple of the new content and the number of
+changes made.
+
+Scripts cannot read or write files or access the network. Besides the
+Starlark built-ins they can use:
+
+	arg                        the optional second argument of the codemod
+	re.search(pattern, s)      first match and its groups as a list, or None
+	re.findall(pattern, s)     every match
+	re.sub(pattern, repl, s, count=0)
+	                           replace count matches, or all if 0, $1 and
+	                           ${name} expand groups
+	re.split(pattern, s)       split around matches
+	json.encode(x), json.decode(s), json.indent(s)
+	yaml.encode(x), yaml.decode(s)
+	shell.split(s)             words of a shell command, without quotes
+	shell.functions(s)         names of the functions in a shell script
+	shell.function(s, name)    source of a shell function, or None
+
+Patterns use Go syntax (https://pkg.go.dev/regexp/syntax).
+
+Example:
+	upstream: https://github.com/community-scripts/ProxmoxVE
+	modsdir: codemods
+	codemods:
+	- description: Fork downloads in curl calls only
+		mod: script
+		match: "**/*.sh"
+		args:
+		- codemods/curl.star
+		- https://github.com/bketelsen/IncusScripts/raw/main
+
+codemods/curl.star:
+	def modify(path, content):
+		lines = content.split("\n")
+		for i, line in enumerate(lines):
+			if "curl" in line:
+				lines[i] = re.sub("https://[^ ]*/raw/main", arg, line)
+		return "\n".join(lines)
+	`
+}
+
+// loadScript runs the script named by args[0], a file of the fork, and
+// returns the thread to run its modify function in
+func loadScript(log *slog.Logger, fork fs.FS, args ...string) (*starlark.Thread, starlark.Callable, error) {
+	src, err := readForkFile(fork, args[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading script: %w", err)
+	}
+	predeclared := scriptModules()
+	predeclared["arg"] = starlark.String("")
+	if len(args) > 1 {
+		predeclared["arg"] = starlark.String(args[1])
+	}
+
+	thread := &starlark.Thread{
+		Name: args[0],
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Info("Script", "script", args[0], "message", msg)
+		},
+		// scripts cannot load other files
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("cannot load %s, scripts cannot load modules", module)
+		},
+	}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+	globals, err := starlark.ExecFileOptions(opts, thread, args[0], src, predeclared)
+	if err != nil {
+		return nil, nil, fmt.Errorf("running script: %w", scriptError(err))
+	}
+	modify, ok := globals["modify"].(starlark.Callable)
+	if !ok {
+		return nil, nil, fmt.Errorf("script %s does not define a modify function", args[0])
+	}
+	return thread, modify, nil
+}
+
+// runScript calls the modify function of a script for one file
+func runScript(thread *starlark.Thread, modify starlark.Callable, path string, content []byte) ([]byte, int, error) {
+	thread.SetMaxExecutionSteps(thread.ExecutionSteps() + scriptMaxSteps)
+	v, err := starlark.Call(thread, modify, starlark.Tuple{starlark.String(path), starlark.String(content)}, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", path, scriptError(err))
+	}
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return content, 0, nil
+	case starlark.String:
+		return []byte(v), 1, nil
+	case starlark.Tuple:
+		if len(v) == 2 {
+			s, ok := v[0].(starlark.String)
+			n, err := starlark.AsInt32(v[1])
+			if ok && err == nil {
+				return []byte(s), n, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("%s: modify returned %s, not a string, None or (string, int)", path, v.Type())
+}
+
+// scriptError adds the Starlark backtrace to errors raised by a script
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
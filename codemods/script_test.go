@@ -0,0 +1,157 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		arg         string
+		content     string
+		expected    string
+		occurrences int
+		expectError string
+	}{
+		{
+			name: "Rewrite URLs in curl calls",
+			script: `
+def modify(path, content):
+    lines = content.split("\n")
+    n = 0
+    for i, line in enumerate(lines):
+        if "curl" in line:
+            lines[i] = re.sub("https://[^ ]*/raw/main", arg, line)
+            n += 1
+    return "\n".join(lines), n
+`,
+			arg:         "https://fork/raw/main",
+			content:     "curl -fsSL https://upstream/raw/main/x.sh\nwget https://upstream/raw/main/y.sh\n",
+			expected:    "curl -fsSL https://fork/raw/main/x.sh\nwget https://upstream/raw/main/y.sh\n",
+			occurrences: 1,
+		},
+		{
+			name: "Bump version below minimum",
+			script: `
+def modify(path, content):
+    m = re.search("version: ([0-9]+)", content)
+    if m == None or int(m[1]) >= 3:
+        return None
+    return re.sub("version: [0-9]+", "version: 3", content)
+`,
+			content:     "name: app\nversion: 2\n",
+			expected:    "name: app\nversion: 3\n",
+			occurrences: 1,
+		},
+		{
+			name: "YAML and JSON",
+			script: `
+def modify(path, content):
+    doc = yaml.decode(content)
+    doc["tags"].append(json.decode('{"name": "fork"}')["name"])
+    doc["path"] = path
+    return yaml.encode(doc)
+`,
+			content:     "name: app\ntags:\n  - upstream\nenabled: true\n",
+			expected:    "name: app\ntags:\n  - upstream\n  - fork\nenabled: true\npath: file.txt\n",
+			occurrences: 1,
+		},
+		{
+			name: "Shell helpers",
+			script: `
+def modify(path, content):
+    words = shell.split("curl -o 'my file' \"$URL\"")
+    return "\n".join(shell.functions(content) + [shell.function(content, "b"), words[2], words[3]])
+`,
+			content:     "a() {\n\techo a\n}\nfunction b {\n\techo b\n}\n",
+			expected:    "a\nb\nfunction b {\n\techo b\n}\nmy file\n\"$URL\"",
+			occurrences: 1,
+		},
+		{
+			name: "Substitute groups with a count",
+			script: `
+def modify(path, content):
+    return re.sub("(?P<name>[a-z]+)=([0-9]+)", "${name}:$2", content, count=2)
+`,
+			content:     "a=1 b=2 c=3",
+			expected:    "a:1 b:2 c=3",
+			occurrences: 1,
+		},
+		{
+			name:        "Negative count",
+			script:      "def modify(path, content):\n    return re.sub('a', 'b', content, -1)\n",
+			content:     "a",
+			expectError: "invalid replacement count -1",
+		},
+		{
+			name:        "No modify function",
+			script:      "x = 1\n",
+			content:     "x",
+			expectError: "does not define a modify function",
+		},
+		{
+			name:        "Wrong return type",
+			script:      "def modify(path, content):\n    return 1\n",
+			content:     "x",
+			expectError: "modify returned int",
+		},
+		{
+			name:        "No loading",
+			script:      "load('other.star', 'x')\ndef modify(path, content):\n    return content\n",
+			content:     "x",
+			expectError: "scripts cannot load modules",
+		},
+		{
+			name:        "Runtime error",
+			script:      "def modify(path, content):\n    fail('broken ' + path)\n",
+			content:     "x",
+			expectError: "broken file.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fork := MemFS{"mod.star": {Data: []byte(tt.script)}}
+			files := MemFS{"file.txt": {Data: []byte(tt.content)}}
+			args := []string{"mod.star", tt.arg}
+
+			res, err := Script{}.Apply(context.Background(), files, fork, []string{"*.txt"}, args...)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, Script{}.Validate(fork, nil, args...))
+			assert.Equal(t, tt.expected, string(files["file.txt"].Data))
+			assert.Equal(t, tt.occurrences, res.Occurrences)
+		})
+	}
+}
+
+func TestScriptCancel(t *testing.T) {
+	fork := MemFS{"loop.star": {Data: []byte("def modify(path, content):\n    while True:\n        pass\n")}}
+	files := MemFS{"file.txt": {Data: []byte("x")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := Script{}.Apply(ctx, files, fork, []string{"*.txt"}, "loop.star")
+	assert.ErrorContains(t, err, "context deadline exceeded")
+}
+
+func TestScriptPrint(t *testing.T) {
+	fork := MemFS{"print.star": {Data: []byte("def modify(path, content):\n    print('modifying ' + path)\n    return content\n")}}
+	files := MemFS{"file.txt": {Data: []byte("x")}}
+
+	var logs bytes.Buffer
+	ctx := WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+	_, err := Script{}.Apply(ctx, files, fork, []string{"*.txt"}, "print.star")
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `message="modifying file.txt"`)
+}
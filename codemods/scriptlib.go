@@ -0,0 +1,361 @@
+package codemods
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"gopkg.in/yaml.v3"
+	"mvdan.cc/sh/syntax"
+)
+
+// scriptModules are the helper modules predeclared for scripts
+func scriptModules() starlark.StringDict {
+	return starlark.StringDict{
+		"json": json.Module,
+		"re": &starlarkstruct.Module{
+			Name: "re",
+			Members: starlark.StringDict{
+				"search":  starlark.NewBuiltin("re.search", reSearch),
+				"findall": starlark.NewBuiltin("re.findall", reFindAll),
+				"sub":     starlark.NewBuiltin("re.sub", reSub),
+				"split":   starlark.NewBuiltin("re.split", reSplit),
+			},
+		},
+		"yaml": &starlarkstruct.Module{
+			Name: "yaml",
+			Members: starlark.StringDict{
+				"decode": starlark.NewBuiltin("yaml.decode", yamlDecode),
+				"encode": starlark.NewBuiltin("yaml.encode", yamlEncode),
+			},
+		},
+		"shell": &starlarkstruct.Module{
+			Name: "shell",
+			Members: starlark.StringDict{
+				"split":     starlark.NewBuiltin("shell.split", shellSplit),
+				"functions": starlark.NewBuiltin("shell.functions", shellFunctions),
+				"function":  starlark.NewBuiltin("shell.function", shellFunction),
+			},
+		},
+	}
+}
+
+// unpackRegexp unpacks a pattern and a string and compiles the pattern
+func unpackRegexp(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (*regexp.Regexp, string, error) {
+	var pattern, s string
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "s", &s)
+	if err != nil {
+		return nil, "", err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return re, s, nil
+}
+
+// reSearch returns the first match of pattern in s and its groups as a
+// list, or None
+func reSearch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegexp(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return starlark.None, nil
+	}
+	return stringList(m), nil
+}
+
+// reFindAll returns every match of pattern in s
+func reFindAll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegexp(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(re.FindAllString(s, -1)), nil
+}
+
+// reSub replaces matches of pattern in s with repl, expanding $1 and
+// ${name}, at most count times, like the regex codemod with a limit.
+// A count of 0 replaces every match.
+func reSub(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, repl, s string
+	var count int
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "repl", &repl, "s", &s, "count?", &count)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("%s: invalid replacement count %d", b.Name(), count)
+	}
+	if count == 0 {
+		count = -1
+	}
+	out, _ := regexReplace(re, repl, count, []byte(s))
+	return starlark.String(out), nil
+}
+
+// reSplit splits s around the matches of pattern
+func reSplit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, s, err := unpackRegexp(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(re.Split(s, -1)), nil
+}
+
+func stringList(ss []string) *starlark.List {
+	values := make([]starlark.Value, len(ss))
+	for i, s := range ss {
+		values[i] = starlark.String(s)
+	}
+	return starlark.NewList(values)
+}
+
+// yamlDecode parses a YAML document into dicts, lists and scalars,
+// keeping the order of mapping keys
+func yamlDecode(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal([]byte(s), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return fromYAML(&doc)
+}
+
+func fromYAML(n *yaml.Node) (starlark.Value, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return starlark.None, nil
+		}
+		return fromYAML(n.Content[0])
+	case yaml.AliasNode:
+		return fromYAML(n.Alias)
+	case yaml.SequenceNode:
+		values := make([]starlark.Value, len(n.Content))
+		for i, c := range n.Content {
+			v, err := fromYAML(c)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return starlark.NewList(values), nil
+	case yaml.MappingNode:
+		d := starlark.NewDict(len(n.Content) / 2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, err := fromYAML(n.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			v, err := fromYAML(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			err = d.SetKey(k, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	var v any
+	err := n.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case string:
+		return starlark.String(v), nil
+	}
+	return starlark.String(n.Value), nil
+}
+
+// yamlEncode formats a value as a YAML document
+func yamlEncode(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var v starlark.Value
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &v)
+	if err != nil {
+		return nil, err
+	}
+	n, err := toYAML(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err = enc.Encode(n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return starlark.String(buf.String()), nil
+}
+
+func toYAML(v starlark.Value) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case starlark.Bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strings.ToLower(v.String())}, nil
+	case starlark.Int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}, nil
+	case starlark.Float:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}, nil
+	case starlark.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v)}, nil
+	case starlark.Indexable: // list and tuple
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := 0; i < v.Len(); i++ {
+			c, err := toYAML(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		return n, nil
+	case *starlark.Dict:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, item := range v.Items() {
+			k, err := toYAML(item[0])
+			if err != nil {
+				return nil, err
+			}
+			c, err := toYAML(item[1])
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, k, c)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot encode %s", v.Type())
+}
+
+// shellSplit splits a shell command line into its words, removing quotes.
+// Words with expansions are returned as written.
+func shellSplit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s)
+	if err != nil {
+		return nil, err
+	}
+	f, err := syntax.NewParser().Parse(strings.NewReader(s), "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	var words []string
+	for _, stmt := range f.Stmts {
+		call, ok := stmt.Cmd.(*syntax.CallExpr)
+		if !ok {
+			return nil, fmt.Errorf("%s: %q is not a simple command", b.Name(), s)
+		}
+		for _, w := range call.Args {
+			words = append(words, shellWord(s, w))
+		}
+	}
+	return stringList(words), nil
+}
+
+// shellWord returns the value of a word without quotes, or its source if
+// it contains expansions
+func shellWord(src string, w *syntax.Word) string {
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(part.Value)
+		case *syntax.SglQuoted:
+			sb.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			for _, p := range part.Parts {
+				lit, ok := p.(*syntax.Lit)
+				if !ok {
+					return src[w.Pos().Offset():w.End().Offset()]
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return src[w.Pos().Offset():w.End().Offset()]
+		}
+	}
+	return sb.String()
+}
+
+// shellFuncs returns the function declarations in a shell script
+func shellFuncs(b *starlark.Builtin, s string) ([]*syntax.FuncDecl, error) {
+	f, err := syntax.NewParser().Parse(strings.NewReader(s), "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	var funcs []*syntax.FuncDecl
+	syntax.Walk(f, func(n syntax.Node) bool {
+		if decl, ok := n.(*syntax.FuncDecl); ok {
+			funcs = append(funcs, decl)
+		}
+		return true
+	})
+	return funcs, nil
+}
+
+// shellFunctions returns the names of the functions a shell script declares
+func shellFunctions(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s)
+	if err != nil {
+		return nil, err
+	}
+	funcs, err := shellFuncs(b, s)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(funcs))
+	for i, decl := range funcs {
+		names[i] = decl.Name.Value
+	}
+	return stringList(names), nil
+}
+
+// shellFunction returns the source of the named function in a shell
+// script, or None
+func shellFunction(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s, name string
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "s", &s, "name", &name)
+	if err != nil {
+		return nil, err
+	}
+	funcs, err := shellFuncs(b, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range funcs {
+		if decl.Name.Value == name {
+			return starlark.String(s[decl.Pos().Offset():decl.End().Offset()]), nil
+		}
+	}
+	return starlark.None, nil
+}
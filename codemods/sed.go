@@ -1,11 +1,10 @@
 package codemods
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strings"
 )
 
@@ -18,31 +17,34 @@ type Sed struct{}
 // assert that Sed implements CodeMod
 var _ CodeMod = Sed{}
 
-func (s Sed) Apply(source, target, match string, args ...string) error {
-	slog.Info("Applying sed", "source", source, "target", target, "match", match, "args", args)
+func (s Sed) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying sed", "match", match, "args", args)
 
-	sourceMatches := filepath.Join(source, match)
-	matches, err := filepath.Glob(sourceMatches)
+	res, err := modifyMatches(ctx, files, match, func(_ string, b []byte) ([]byte, int, error) {
+		return sedReplace(args[0], args[1], b), strings.Count(string(b), args[0]), nil
+	})
 	if err != nil {
-		return fmt.Errorf("globbing source: %w", err)
-	}
-	for _, m := range matches {
-		err = sed(args[0], args[1], m)
-		if err != nil {
-			return fmt.Errorf("applying sed: %w", err)
-		}
+		return res, fmt.Errorf("applying sed: %w", err)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (s Sed) Validate(_, _, _ string, args ...string) error {
+func (s Sed) Validate(_ fs.FS, _ []string, args ...string) error {
 	if len(args) != 2 {
 		return errors.New("sed requires two arguments")
 	}
 	return nil
 }
 
+func (s Sed) Args() []Arg {
+	return []Arg{
+		{Name: "search", Type: ArgString, Required: true, Description: "string to search for"},
+		{Name: "replacement", Type: ArgString, Required: true, Description: "string to replace it with"},
+	}
+}
+
 func (s Sed) Description() string {
 	return "Replace strings in a file"
 }
@@ -52,10 +54,6 @@ func (s Sed) Usage() string {
 This codemod replaces strings in the matched file(s) with a string
 specified in the arguments.
 
-Args (2 required):
-	1. search string
-	2. replacement string
-
 Example:
 	upstream: https://github.com/community-scripts/ProxmoxVE
 	modsdir: codemods
@@ -74,28 +72,3 @@ func sedReplace(old, newthing string, fileContent []byte) []byte {
 	fileString = strings.ReplaceAll(fileString, old, newthing)
 	return []byte(fileString)
 }
-
-func sed(old, newthing, filePath string) error {
-	// Read the original file content
-	fileData, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	// Perform the replacement
-	modifiedData := sedReplace(old, newthing, fileData)
-
-	// Get file permissions
-	fi, err := os.Stat(filePath)
-	if err != nil {
-		return err
-	}
-
-	// Write the modified content back to the file
-	err = os.WriteFile(filePath, modifiedData, fi.Mode())
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
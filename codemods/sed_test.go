@@ -1,9 +1,12 @@
 package codemods
 
 import (
+	"context"
+	"io/fs"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSedReplace(t *testing.T) {
@@ -52,3 +55,39 @@ func TestSedReplace(t *testing.T) {
 		})
 	}
 }
+
+func TestSedApplyResult(t *testing.T) {
+	files := MemFS{
+		"a.sh": {Data: []byte("echo foo"), Mode: 0o755},
+		"b.sh": {Data: []byte("echo baz")},
+	}
+	res, err := Sed{}.Apply(context.Background(), files, MemFS{}, []string{"*.sh"}, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: 2, Changed: 1, Files: []string{"a.sh"}, Bytes: 3, Occurrences: 1}, res)
+	assert.Equal(t, "echo bar", string(files["a.sh"].Data))
+	assert.Equal(t, fs.FileMode(0o755), files["a.sh"].Mode)
+}
+
+func TestSedApplyCancelled(t *testing.T) {
+	files := MemFS{"a.sh": {Data: []byte("echo foo")}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Sed{}.Apply(ctx, files, MemFS{}, []string{"*.sh"}, "foo", "bar")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "echo foo", string(files["a.sh"].Data))
+}
+
+func TestSedApplyDirectory(t *testing.T) {
+	files := MemFS{
+		"misc/build.func":     {Data: []byte("echo foo")},
+		"misc/sub/tools.func": {Data: []byte("echo baz")},
+		"install.sh":          {Data: []byte("echo foo")},
+	}
+	// '**' matches the directories below misc too, only files are modified
+	res, err := Sed{}.Apply(context.Background(), files, MemFS{}, []string{"misc/**"}, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Matched)
+	assert.Equal(t, []string{"misc/build.func"}, res.Files)
+	assert.Equal(t, "echo foo", string(files["install.sh"].Data))
+}
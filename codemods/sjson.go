@@ -1,11 +1,10 @@
 package codemods
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"io/fs"
 
 	"github.com/tidwall/sjson"
 )
@@ -19,41 +18,58 @@ type SJSON struct{}
 // assert that Inject implements CodeMod
 var _ CodeMod = SJSON{}
 
-func (s SJSON) Apply(source, target, match string, args ...string) error {
-	slog.Info("Applying sjson", "source", source, "target", target, "match", match, "args", args)
+func (s SJSON) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying sjson", "match", match, "args", args)
 
-	sourceMatches := filepath.Join(source, match)
-	matches, err := filepath.Glob(sourceMatches)
+	action := args[0]
+	key := args[1]
+	var value string
+	if len(args) == 3 {
+		value = args[2]
+	}
+	res, err := modifyMatches(ctx, files, match, func(name string, b []byte) ([]byte, int, error) {
+		log.Debug("Modifying json", "file", name, "action", action, "key", key, "value", value)
+		output, err := modifyJSON(action, key, value, string(b))
+		return []byte(output), 1, err
+	})
 	if err != nil {
-		return fmt.Errorf("globbing source: %w", err)
+		return res, fmt.Errorf("modifying json: %w", err)
 	}
-	for _, m := range matches {
-		action := args[0]
-		key := args[1]
-		var value string
-		if len(args) == 3 {
-			value = args[2]
-		}
 
-		output, err := apply(action, key, value, m)
-		if err != nil {
-			return fmt.Errorf("modifying json: %w", err)
-		}
+	return res, nil
+}
 
-		err = os.WriteFile(m, []byte(output), 0o644)
-		if err != nil {
-			return fmt.Errorf("writing file: %w", err)
+func (s SJSON) Validate(_ fs.FS, _ []string, args ...string) error {
+	if len(args) < 2 {
+		return errors.New("sjson requires at least two arguments")
+	}
+	var value string
+	switch args[0] {
+	case "set":
+		if len(args) != 3 {
+			return errors.New("sjson set requires three arguments")
+		}
+		value = args[2]
+	case "del":
+		if len(args) != 2 {
+			return errors.New("sjson del requires two arguments")
 		}
 	}
-
+	// an empty document shows whether the action and key path are usable
+	_, err := modifyJSON(args[0], args[1], value, "{}")
+	if err != nil {
+		return fmt.Errorf("invalid key path %q: %w", args[1], err)
+	}
 	return nil
 }
 
-func (s SJSON) Validate(_, _, _ string, args ...string) error {
-	if len(args) < 2 {
-		return errors.New("sjson requires at least two arguments")
+func (s SJSON) Args() []Arg {
+	return []Arg{
+		{Name: "action", Type: ArgString, Required: true, Values: []string{"set", "del"}, Description: "modification to make"},
+		{Name: "key", Type: ArgString, Required: true, Description: "key path (https://github.com/tidwall/sjson#path-syntax)"},
+		{Name: "value", Type: ArgString, Description: "value to set, required for set"},
 	}
-	return nil
 }
 
 func (s SJSON) Description() string {
@@ -64,11 +80,6 @@ func (s SJSON) Usage() string {
 	return `sjson modifies a JSON file in-place.
 This codemod modifies the matched file(s) by injecting specified content.
 
-Args (3 required for set, 2 required for del):
-	1. Action (set, del)
-	2. Key path
-	3. Value (required for set)
-
 Example:
 	upstream: https://github.com/community-scripts/ProxmoxVE
 	modsdir: codemods
@@ -76,10 +87,10 @@ Example:
 	- description: change OS Key to debian
 		mod: sjson
 		match: json/debian-vm.json
-		args:
-		- set
-		- install_methods.1.resources.os
-		- debian
+		with:
+		  action: set
+		  key: install_methods.1.resources.os
+		  value: debian
 	`
 }
 
@@ -89,14 +100,12 @@ func modifyJSON(action, key, value, content string) (string, error) {
 
 	switch action {
 	case "set":
-		slog.Debug("Setting", "key", key, "to", value)
 		output, err = sjson.Set(content, key, value)
 		if err != nil {
 			return "", err
 		}
 
 	case "del":
-		slog.Debug("Deleting", "key", key)
 		output, err = sjson.Delete(content, key)
 		if err != nil {
 			return "", err
@@ -108,20 +117,3 @@ func modifyJSON(action, key, value, content string) (string, error) {
 
 	return output, nil
 }
-
-func apply(action, key, value, filePath string) (string, error) {
-	// Read the file content
-	bb, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", err
-	}
-
-	// Modify the JSON content
-	content := string(bb)
-	output, err := modifyJSON(action, key, value, content)
-	if err != nil {
-		return "", err
-	}
-
-	return output, nil
-}
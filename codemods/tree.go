@@ -0,0 +1,217 @@
+package codemods
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// Tree is an FS holding the changes codemods make to a base file system in
+// memory. Each file is read from the base the first time a codemod needs
+// it and passed from codemod to codemod in memory. The base is untouched
+// until Flush writes the changed files to it, so a failing codemod leaves
+// nothing half done: drop the tree instead of flushing it. Trees over a
+// read-only base, like GitFS, are never flushed.
+type Tree struct {
+	base  fs.FS
+	files map[string]*treeFile // files read or changed, by path
+}
+
+// treeFile is the content of a file in a Tree
+type treeFile struct {
+	content []byte // nil if the file was removed
+	mode    fs.FileMode
+	dirty   bool // content differs from the base
+}
+
+// assert that Tree implements FS
+var _ FS = &Tree{}
+
+// NewTree returns a tree changing the files of base
+func NewTree(base fs.FS) *Tree {
+	return &Tree{base: base, files: map[string]*treeFile{}}
+}
+
+// file returns the file name, reading it from the base if needed
+func (t *Tree) file(op, name string) (*treeFile, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := t.files[name]; ok {
+		if f.content == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		return f, nil
+	}
+	fi, err := fs.Stat(t.base, name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("is a directory")}
+	}
+	bb, err := fs.ReadFile(t.base, name)
+	if err != nil {
+		return nil, err
+	}
+	if bb == nil {
+		bb = []byte{}
+	}
+	f := &treeFile{content: bb, mode: fi.Mode()}
+	t.files[name] = f
+	return f, nil
+}
+
+func (t *Tree) Open(name string) (fs.File, error) {
+	if f, ok := t.files[name]; ok {
+		if f.content == nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return newMemFile(name, f.content, f.mode), nil
+	}
+	fi, err := t.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return t.base.Open(name)
+	}
+	entries, err := t.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &memDir{info: fi, entries: entries}, nil
+}
+
+// ReadFile returns the content of the file name with the changes made so
+// far
+func (t *Tree) ReadFile(name string) ([]byte, error) {
+	f, err := t.file("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(f.content), nil
+}
+
+func (t *Tree) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := t.files[name]; ok {
+		if f.content == nil {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+		return fileInfo{name: dirInfo(name).name, size: int64(len(f.content)), mode: f.mode}, nil
+	}
+	fi, err := fs.Stat(t.base, name)
+	if errors.Is(err, fs.ErrNotExist) && t.created(name) {
+		return dirInfo(name), nil
+	}
+	return fi, err
+}
+
+// ReadDir lists the directory name, including the files created and
+// leaving out those removed by codemods
+func (t *Tree) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(t.base, name)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && t.created(name)) {
+		return nil, err
+	}
+	byName := map[string]fs.DirEntry{}
+	for _, e := range entries {
+		byName[e.Name()] = e
+	}
+	for p, f := range t.files {
+		child, isFile, ok := childOf(name, p)
+		switch {
+		case !ok || !f.dirty:
+		case isFile && f.content == nil:
+			delete(byName, child)
+		case isFile:
+			byName[child] = fileInfo{name: child, size: int64(len(f.content)), mode: f.mode}
+		case f.content != nil && byName[child] == nil:
+			byName[child] = dirInfo(child)
+		}
+	}
+	return sortedEntries(byName), nil
+}
+
+// created reports whether codemods created files below the directory name
+func (t *Tree) created(name string) bool {
+	for p, f := range t.files {
+		if _, _, ok := childOf(name, p); ok && f.content != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tree) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := t.file("write", name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		f = &treeFile{mode: perm}
+		t.files[name] = f
+	case err != nil:
+		return err
+	}
+	f.content = bytes.Clone(data)
+	if f.content == nil {
+		f.content = []byte{}
+	}
+	f.dirty = true
+	return nil
+}
+
+func (t *Tree) Remove(name string) error {
+	f, err := t.file("remove", name)
+	if err != nil {
+		return err
+	}
+	f.content = nil
+	f.dirty = true
+	return nil
+}
+
+// Changed returns the paths of the files that differ from the base
+func (t *Tree) Changed() []string {
+	var names []string
+	for name, f := range t.files {
+		if f.dirty {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flush writes the changed files to the base, which must be an FS
+func (t *Tree) Flush() error {
+	changed := t.Changed()
+	if len(changed) == 0 {
+		return nil
+	}
+	base, ok := t.base.(FS)
+	if !ok {
+		return errors.New("the files of the tree are read-only")
+	}
+	for _, name := range changed {
+		f := t.files[name]
+		if f.content == nil {
+			err := base.Remove(name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			delete(t.files, name)
+			continue
+		}
+		err := base.WriteFile(name, f.content, f.mode)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+		f.dirty = false
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package codemods
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "ct"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "ct", "a.sh"), []byte("a"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "ct", "b.sh"), []byte("b"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("readme"), 0o644))
+
+	tree := NewTree(DirFS(root))
+	require.NoError(t, tree.WriteFile("ct/a.sh", []byte("A"), 0o644))
+	require.NoError(t, tree.Remove("ct/b.sh"))
+	require.NoError(t, tree.WriteFile("ct/new/c.sh", []byte("c"), 0o600))
+	_, err := tree.ReadFile("ct/b.sh")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = tree.ReadFile("../outside")
+	assert.Error(t, err)
+	fi, err := tree.Stat("ct/new")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	matches, err := Glob(tree, []string{"ct/**/*.sh"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ct/a.sh", "ct/new/c.sh"}, matches)
+	assert.Equal(t, []string{"ct/a.sh", "ct/b.sh", "ct/new/c.sh"}, tree.Changed())
+	require.NoError(t, fstest.TestFS(tree, "README.md", "ct/a.sh", "ct/new/c.sh"))
+	bb, err := fs.ReadFile(tree, "ct/a.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "A", string(bb))
+
+	// nothing is written before Flush
+	bb, err = os.ReadFile(filepath.Join(root, "ct", "a.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(bb))
+	assert.FileExists(t, filepath.Join(root, "ct", "b.sh"))
+
+	require.NoError(t, tree.Flush())
+	assert.Empty(t, tree.Changed())
+	bb, err = os.ReadFile(filepath.Join(root, "ct", "a.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "A", string(bb))
+	fi, err = os.Stat(filepath.Join(root, "ct", "a.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), fi.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(root, "ct", "b.sh"))
+	fi, err = os.Stat(filepath.Join(root, "ct", "new", "c.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+}
+
+func TestTreeReadOnly(t *testing.T) {
+	tree := NewTree(os.DirFS(t.TempDir()))
+	require.NoError(t, tree.Flush(), "an unchanged tree has nothing to write")
+	require.NoError(t, tree.WriteFile("a.txt", []byte("a"), 0o644))
+	assert.EqualError(t, tree.Flush(), "the files of the tree are read-only")
+}
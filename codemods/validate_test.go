@@ -0,0 +1,46 @@
+package codemods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	fork := MemFS{
+		"ok.sh":       {Data: []byte("function ok() {\n\techo ok\n}\n")},
+		"bad.sh":      {Data: []byte("function bad() {\n")},
+		"dir/file.sh": {Data: []byte("echo")},
+	}
+
+	tests := []struct {
+		name        string
+		mod         string
+		args        []string
+		expectError string
+	}{
+		{name: "Replacement function", mod: "bashfunc", args: []string{"ok", "ok.sh"}},
+		{name: "Missing replacement function", mod: "bashfunc", args: []string{"ok", "missing.sh"}, expectError: "reading replacement function"},
+		{name: "Unparsable replacement function", mod: "bashfunc", args: []string{"bad", "bad.sh"}, expectError: "parsing replacement function"},
+		{name: "Replacement file", mod: "replacefile", args: []string{"ok.sh"}},
+		{name: "Replacement file arguments", mod: "replacefile", args: []string{"ok.sh", "bad.sh"}, expectError: "replacefile requires one argument"},
+		{name: "Missing replacement file", mod: "replacefile", args: []string{"missing.sh"}, expectError: "replacement file"},
+		{name: "Replacement directory", mod: "replacefile", args: []string{"dir"}, expectError: "is a directory"},
+		{name: "Injection line", mod: "inject", args: []string{"3", "x"}},
+		{name: "Bad injection point", mod: "inject", args: []string{"middle", "x"}, expectError: "invalid injection point"},
+		{name: "Bad regex", mod: "regex", args: []string{"a(", "b"}, expectError: "missing closing )"},
+		{name: "JSON set without value", mod: "sjson", args: []string{"set", "a.b"}, expectError: "sjson set requires three arguments"},
+		{name: "Bad YAML value", mod: "yamlpath", args: []string{"set", "a.b", "[x"}, expectError: "parsing value"},
+		{name: "Empty YAML key", mod: "yamlpath", args: []string{"del", "a..b"}, expectError: "keys cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Mods[tt.mod].Validate(fork, []string{"*.sh"}, tt.args...)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package codemods
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func init() {
+	Mods["yamlpath"] = YAMLPath{}
+}
+
+type YAMLPath struct{}
+
+// assert that YAMLPath implements CodeMod
+var _ CodeMod = YAMLPath{}
+
+func (s YAMLPath) Apply(ctx context.Context, files FS, _ fs.FS, match []string, args ...string) (Result, error) {
+	log := Logger(ctx)
+	log.Info("Applying yamlpath", "match", match, "args", args)
+
+	action := args[0]
+	key := args[1]
+	var value string
+	if len(args) == 3 {
+		value = args[2]
+	}
+	res, err := modifyMatches(ctx, files, match, func(path string, b []byte) ([]byte, int, error) {
+		log.Debug("Modifying yaml", "file", path, "action", action, "key", key, "value", value)
+		output, n, err := modifyYAML(action, key, value, b)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+		return output, n, nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("modifying yaml: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s YAMLPath) Validate(_ fs.FS, _ []string, args ...string) error {
+	if len(args) < 2 {
+		return errors.New("yamlpath requires at least two arguments")
+	}
+	switch args[0] {
+	case "set", "append":
+		if len(args) != 3 {
+			return fmt.Errorf("yamlpath %s requires three arguments", args[0])
+		}
+		_, err := parseYAMLValue(args[2])
+		if err != nil {
+			return err
+		}
+	case "del":
+		if len(args) != 2 {
+			return errors.New("yamlpath del requires two arguments")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", args[0])
+	}
+	if slices.Contains(splitKeyPath(args[1]), "") {
+		return fmt.Errorf("invalid key path %q, keys cannot be empty", args[1])
+	}
+	return nil
+}
+
+func (s YAMLPath) Args() []Arg {
+	return []Arg{
+		{Name: "action", Type: ArgString, Required: true, Values: []string{"set", "del", "append"}, Description: "modification to make"},
+		{Name: "key", Type: ArgString, Required: true, Description: `dot separated key path, \. escapes a dot in a key`},
+		{Name: "value", Type: ArgString, Description: "YAML value to set or append, required for set and append"},
+	}
+}
+
+func (s YAMLPath) Description() string {
+	return "Modify a YAML file in-place"
+}
+
+func (s YAMLPath) Usage() string {
+	return `yamlpath modifies a YAML file in-place.
+This codemod sets, deletes or appends values in the matched YAML
+file(s), keeping comments, key order and indentation intact.
+
+Key paths are dotted, like the sjson codemod: "jobs.build.steps.0.uses".
+Numeric segments index sequences, and a literal dot in a key can be
+escaped with a backslash. Only the first document of a multi-document
+file is modified, the others are written back unchanged.
+
+Values are parsed as YAML, so "3", "true", "[a, b]" and "{k: v}" keep
+their types. Quote the value to force a string.
+
+Files are left untouched when the key already holds the value or the key
+to delete does not exist.
+
+Example:
+	upstream: https://github.com/community-scripts/ProxmoxVE
+	modsdir: codemods
+	codemods:
+	- description: pin the checkout action
+		mod: yamlpath
+		match: .github/workflows/*.yml
+		args:
+		- set
+		- jobs.build.steps.0.uses
+		- actions/checkout@v4
+	`
+}
+
+// splitKeyPath splits a dotted key path, honouring backslash escapes
+func splitKeyPath(key string) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key):
+			i++
+			cur.WriteByte(key[i])
+		case key[i] == '.':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(key[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+// yamlIndent guesses the indentation width used by a YAML document
+func yamlIndent(content []byte) int {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 {
+			return n
+		}
+	}
+	return 2
+}
+
+func parseYAMLValue(value string) (*yaml.Node, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(value), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing value: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// modifyYAML changes the first document of content, keeping the others,
+// and returns the new content with the number of values changed. Content
+// is returned as is if nothing changed.
+func modifyYAML(action, key, value string, content []byte) ([]byte, int, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		docs = append(docs, &doc)
+	}
+	if len(docs) == 0 {
+		docs = append(docs, &yaml.Node{Kind: yaml.DocumentNode})
+	}
+	if len(docs[0].Content) == 0 {
+		docs[0].Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := docs[0].Content[0]
+	path := splitKeyPath(key)
+
+	switch action {
+	case "set":
+		v, err := parseYAMLValue(value)
+		if err != nil {
+			return nil, 0, err
+		}
+		old, err := yamlLookup(root, path, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		if old != nil && yamlEqual(old, v) {
+			return content, 0, nil
+		}
+		err = yamlSet(root, path, v)
+		if err != nil {
+			return nil, 0, err
+		}
+
+	case "append":
+		v, err := parseYAMLValue(value)
+		if err != nil {
+			return nil, 0, err
+		}
+		seq, err := yamlLookup(root, path, true)
+		if err != nil {
+			return nil, 0, err
+		}
+		if seq.Kind == 0 || (seq.Kind == yaml.ScalarNode && seq.Tag == "!!null") {
+			*seq = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		if seq.Kind != yaml.SequenceNode {
+			return nil, 0, fmt.Errorf("%q is not a sequence", key)
+		}
+		seq.Content = append(seq.Content, v)
+
+	case "del":
+		parent, err := yamlLookup(root, path[:len(path)-1], false)
+		if err != nil {
+			return nil, 0, err
+		}
+		if parent == nil {
+			return content, 0, nil
+		}
+		deleted, err := yamlDelete(parent, path[len(path)-1])
+		if err != nil {
+			return nil, 0, err
+		}
+		if !deleted {
+			return content, 0, nil
+		}
+
+	default:
+		return nil, 0, fmt.Errorf("unknown action %q", action)
+	}
+
+	var buf bytes.Buffer
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("---")) {
+		buf.WriteString("---\n")
+	}
+	indent := yamlIndent(content)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	for _, doc := range docs {
+		err := enc.Encode(doc)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	err := enc.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+	output := buf.Bytes()
+	if yamlCompactSeq(content) {
+		output = compactSeqs(output, indent)
+	}
+	return keepBlankLines(content, output), 1, nil
+}
+
+// yamlEqual reports whether the nodes a and b hold the same value,
+// whatever their style
+func yamlEqual(a, b *yaml.Node) bool {
+	var va, vb any
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// keepBlankLines puts back the blank lines of content, which the encoder
+// drops, before the lines of output they preceded
+func keepBlankLines(content, output []byte) []byte {
+	type anchor struct {
+		line string
+		n    int // occurrences of line before this one
+	}
+	blanks := map[anchor]int{}
+	seen := map[string]int{}
+	pending := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == "" {
+			pending++
+			continue
+		}
+		a := anchor{line, seen[line]}
+		seen[line]++
+		blanks[a] = pending
+		pending = 0
+	}
+
+	var lines []string
+	seen = map[string]int{}
+	pending = 0
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == "" {
+			// blank lines of block scalars are kept by the encoder
+			pending++
+			lines = append(lines, line)
+			continue
+		}
+		a := anchor{line, seen[line]}
+		seen[line]++
+		for range blanks[a] - pending {
+			lines = append(lines, "")
+		}
+		pending = 0
+		lines = append(lines, line)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// yamlKeyColumn returns the column of the key of a line holding only a
+// mapping key, like "key:" or "- key:", or -1 for other lines
+func yamlKeyColumn(line string) int {
+	trimmed := strings.TrimLeft(line, " ")
+	code := strings.TrimSpace(trimmed)
+	if !strings.HasSuffix(code, ":") || strings.HasPrefix(code, "#") {
+		return -1
+	}
+	col := len(line) - len(trimmed)
+	if strings.HasPrefix(trimmed, "- ") {
+		col += 2
+	}
+	return col
+}
+
+// yamlCompactSeq reports whether the sequences in a mapping of content are
+// written at the indentation of their key, as in "key:\n- item"
+func yamlCompactSeq(content []byte) bool {
+	key := -1
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if key >= 0 && (trimmed == "-" || strings.HasPrefix(trimmed, "- ")) {
+			return len(line)-len(trimmed) == key
+		}
+		key = yamlKeyColumn(line)
+	}
+	return false
+}
+
+// compactSeqs moves the sequences in a mapping, which the encoder indents
+// below their key, back to the indentation of the key
+func compactSeqs(content []byte, indent int) []byte {
+	type region struct{ col, shift int }
+	var regions []region // sequences being moved, by original column
+	key := -1
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		col := len(line) - len(trimmed)
+		for len(regions) > 0 && col < regions[len(regions)-1].col {
+			regions = regions[:len(regions)-1]
+		}
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		if isItem && key >= 0 && col == key+indent {
+			regions = append(regions, region{col: col, shift: indent})
+		}
+		shift := 0
+		for _, r := range regions {
+			shift += r.shift
+		}
+		lines[i] = line[shift:]
+		if !strings.HasPrefix(trimmed, "#") {
+			key = yamlKeyColumn(line)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// yamlLookup walks path below node and returns the node it points to.
+// If create is set, missing mapping keys are added and a sequence index
+// equal to the sequence length appends a new element; otherwise a missing
+// path returns nil.
+func yamlLookup(node *yaml.Node, path []string, create bool) (*yaml.Node, error) {
+	for i, part := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == part {
+					next = node.Content[j+1]
+					break
+				}
+			}
+			if next == nil {
+				if !create {
+					return nil, nil
+				}
+				next = &yaml.Node{}
+				node.Content = append(node.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part},
+					next)
+			}
+			node = next
+
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a sequence index", strings.Join(path[:i+1], "."))
+			}
+			switch {
+			case idx >= 0 && idx < len(node.Content):
+				node = node.Content[idx]
+			case create && (idx == len(node.Content) || idx == -1):
+				next := &yaml.Node{}
+				node.Content = append(node.Content, next)
+				node = next
+			case create:
+				return nil, fmt.Errorf("index %d out of range at %q", idx, strings.Join(path[:i+1], "."))
+			default:
+				return nil, nil
+			}
+
+		default:
+			if !create {
+				return nil, nil
+			}
+			if node.Kind != 0 && !(node.Kind == yaml.ScalarNode && node.Tag == "!!null") {
+				return nil, fmt.Errorf("%q is not a mapping or sequence", strings.Join(path[:i], "."))
+			}
+			// an empty or null node becomes a container
+			if _, err := strconv.Atoi(part); err == nil {
+				*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			} else {
+				*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			return yamlLookup(node, path[i:], create)
+		}
+	}
+	return node, nil
+}
+
+// yamlSet replaces the node at path with value, keeping its comments
+func yamlSet(root *yaml.Node, path []string, value *yaml.Node) error {
+	node, err := yamlLookup(root, path, true)
+	if err != nil {
+		return err
+	}
+	head, line, foot := node.HeadComment, node.LineComment, node.FootComment
+	*node = *value
+	if node.HeadComment == "" {
+		node.HeadComment = head
+	}
+	if node.LineComment == "" {
+		node.LineComment = line
+	}
+	if node.FootComment == "" {
+		node.FootComment = foot
+	}
+	return nil
+}
+
+// yamlDelete removes key from a mapping or sequence node and reports
+// whether it was there
+func yamlDelete(node *yaml.Node, key string) (bool, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				node.Content = append(node.Content[:j], node.Content[j+2:]...)
+				return true, nil
+			}
+		}
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(key)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a sequence index", key)
+		}
+		if idx >= 0 && idx < len(node.Content) {
+			node.Content = append(node.Content[:idx], node.Content[idx+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
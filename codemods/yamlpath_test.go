@@ -0,0 +1,184 @@
+package codemods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModifyYAML(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		key         string
+		value       string
+		content     string
+		expected    string
+		occurrences int
+		expectError bool
+	}{
+		{
+			name:   "Set existing key keeps comments and order",
+			action: "set",
+			key:    "jobs.build.runs-on",
+			value:  "self-hosted",
+			content: `# workflow
+name: ci
+jobs:
+  build:
+    runs-on: ubuntu-latest # the runner
+    steps:
+      - uses: actions/checkout@v3
+`,
+			expected: `# workflow
+name: ci
+jobs:
+  build:
+    runs-on: self-hosted # the runner
+    steps:
+      - uses: actions/checkout@v3
+`,
+			occurrences: 1,
+		},
+		{
+			name:        "Set non-existing key",
+			action:      "set",
+			key:         "foo.bar",
+			value:       "baz",
+			content:     "foo:\n  qux: 1\n",
+			expected:    "foo:\n  qux: 1\n  bar: baz\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Set sequence element",
+			action:      "set",
+			key:         "steps.0.uses",
+			value:       "actions/checkout@v4",
+			content:     "steps:\n  - uses: actions/checkout@v3\n",
+			expected:    "steps:\n  - uses: actions/checkout@v4\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Set typed value",
+			action:      "set",
+			key:         "replicas",
+			value:       "3",
+			content:     "replicas: 1\n",
+			expected:    "replicas: 3\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Set escaped key",
+			action:      "set",
+			key:         `labels.app\.kubernetes\.io/name`,
+			value:       "surgeon",
+			content:     "labels:\n    app.kubernetes.io/name: upstream\n",
+			expected:    "labels:\n    app.kubernetes.io/name: surgeon\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Delete key",
+			action:      "del",
+			key:         "foo.bar",
+			content:     "foo:\n  bar: baz\n  qux: 1\n",
+			expected:    "foo:\n  qux: 1\n",
+			occurrences: 1,
+		},
+		{
+			name:     "Delete missing key",
+			action:   "del",
+			key:      "foo.missing.bar",
+			content:  "foo:\n  bar: baz\n",
+			expected: "foo:\n  bar: baz\n",
+		},
+		{
+			name:        "Append to sequence",
+			action:      "append",
+			key:         "services",
+			value:       "redis",
+			content:     "services:\n  - postgres\n",
+			expected:    "services:\n  - postgres\n  - redis\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Append creates sequence",
+			action:      "append",
+			key:         "services",
+			value:       "redis",
+			content:     "name: app\n",
+			expected:    "name: app\nservices:\n  - redis\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Keep other documents",
+			action:      "set",
+			key:         "a",
+			value:       "2",
+			content:     "a: 1\n---\nb: 2\n---\nc: [x]\n",
+			expected:    "a: 2\n---\nb: 2\n---\nc: [x]\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Keep compact sequences",
+			action:      "set",
+			key:         "jobs.build.runs-on",
+			value:       "self-hosted",
+			content:     "jobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n    - uses: actions/checkout@v4\n      with:\n        args:\n        - a\n    - run: make\non:\n- push\n",
+			expected:    "jobs:\n  build:\n    runs-on: self-hosted\n    steps:\n    - uses: actions/checkout@v4\n      with:\n        args:\n        - a\n    - run: make\non:\n- push\n",
+			occurrences: 1,
+		},
+		{
+			name:     "Set equal value leaves the file untouched",
+			action:   "set",
+			key:      "jobs.build.runs-on",
+			value:    "ubuntu-latest",
+			content:  "jobs:\n\n  build:\n      runs-on: 'ubuntu-latest'\n",
+			expected: "jobs:\n\n  build:\n      runs-on: 'ubuntu-latest'\n",
+		},
+		{
+			name:     "Delete missing key leaves the file untouched",
+			action:   "del",
+			key:      "foo.missing",
+			content:  "foo:   {bar: baz}\n\nqux: [1,2]\n",
+			expected: "foo:   {bar: baz}\n\nqux: [1,2]\n",
+		},
+		{
+			name:        "Keep blank lines",
+			action:      "set",
+			key:         "b.c",
+			value:       "2",
+			content:     "a: 1\n\nb:\n  c: 1\n\n\n  d: |\n    x\n\n    y\n",
+			expected:    "a: 1\n\nb:\n  c: 2\n\n\n  d: |\n    x\n\n    y\n",
+			occurrences: 1,
+		},
+		{
+			name:        "Append to mapping",
+			action:      "append",
+			key:         "foo",
+			value:       "bar",
+			content:     "foo:\n  bar: baz\n",
+			expectError: true,
+		},
+		{
+			name:        "Unknown action",
+			action:      "unknown",
+			key:         "foo",
+			content:     "foo: bar\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, n, err := modifyYAML(tt.action, tt.key, tt.value, []byte(tt.content))
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, string(result))
+				assert.Equal(t, tt.occurrences, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package surgeon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// maxCommitSubjects limits the upstream commits listed in a commit message
+const maxCommitSubjects = 50
+
+// commit stages the files changed by the sync and commits them to the fork
+func (p *patient) commit() error {
+	if len(p.conflicted) > 0 {
+		p.log.Warn("Not committing, resolve the conflicting files first", "files", p.conflicted)
+		return nil
+	}
+	if len(p.copied) == 0 && len(p.deleted) == 0 && !p.lockChanged {
+		p.log.Info("Nothing to commit")
+		return nil
+	}
+
+	author, err := p.commitAuthor()
+	if err != nil {
+		return err
+	}
+	w, err := p.forkRepo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if branch := p.Config.Commit.Branch; branch != "" {
+		p.log.Info("Creating branch", "branch", branch)
+		err = w.Checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName(branch),
+			Create: true,
+			Keep:   true,
+		})
+		if err != nil {
+			return fmt.Errorf("creating branch %s: %w", branch, err)
+		}
+	}
+
+	// the files are staged without asking for the status of the worktree
+	// each time, which reads every file of the fork
+	for _, path := range append([]string{LockFile}, p.copied...) {
+		p.log.Debug("Staging file", "file", path)
+		err = w.AddWithOptions(&git.AddOptions{Path: path, SkipStatus: true})
+		if err != nil {
+			return fmt.Errorf("staging %s: %w", path, err)
+		}
+	}
+	for _, path := range p.deleted {
+		p.log.Debug("Staging removed file", "file", path)
+		_, err = w.Remove(path)
+		// files the fork never committed are not in the index
+		if err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+			return fmt.Errorf("staging %s: %w", path, err)
+		}
+	}
+
+	msg, err := p.commitMessage()
+	if err != nil {
+		return err
+	}
+	hash, err := w.Commit(msg, &git.CommitOptions{Author: author})
+	if err != nil {
+		return err
+	}
+	p.log.Info("Committed sync results", "commit", hash.String())
+	p.report.Committed = hash.String()
+	_, _ = fmt.Fprintf(p.Out, "Committed %s\n", hash.String())
+	return nil
+}
+
+// commitAuthor returns the configured author, falling back to the git config
+func (p *patient) commitAuthor() (*object.Signature, error) {
+	name, email := p.Config.Commit.AuthorName, p.Config.Commit.AuthorEmail
+	if name == "" || email == "" {
+		cfg, err := p.forkRepo.ConfigScoped(config.GlobalScope)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = cfg.User.Name
+		}
+		if email == "" {
+			email = cfg.User.Email
+		}
+	}
+	if name == "" || email == "" {
+		return nil, errors.New("no commit author, set commit.author_name and commit.author_email or user.name and user.email in git")
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+// commitMessage describes the upstream commits that came in and the
+// codemods that were applied
+func (p *patient) commitMessage() (string, error) {
+	head, err := p.upstreamCommit()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Sync with upstream %s\n\n", shortHash(head))
+	if p.lastSynced != "" && p.lastSynced != head {
+		fmt.Fprintf(&sb, "Upstream: %s %s..%s\n", p.Config.Upstream, shortHash(p.lastSynced), shortHash(head))
+	} else {
+		fmt.Fprintf(&sb, "Upstream: %s %s\n", p.Config.Upstream, shortHash(head))
+	}
+
+	subjects, more, err := p.upstreamSubjects(head)
+	if err != nil {
+		return "", err
+	}
+	if len(subjects) > 0 {
+		sb.WriteString("\nUpstream commits:\n")
+		for _, s := range subjects {
+			fmt.Fprintf(&sb, "  %s\n", s)
+		}
+		if more {
+			sb.WriteString("  ...\n")
+		}
+	}
+
+	if len(p.results) > 0 {
+		sb.WriteString("\nCodemods:\n")
+		for _, r := range p.results {
+			fmt.Fprintf(&sb, "  %s: %s\n", r.Mod, r.Description)
+		}
+	}
+
+	if len(p.Config.Commit.Trailers) > 0 {
+		sb.WriteString("\n")
+		for _, t := range p.Config.Commit.Trailers {
+			fmt.Fprintf(&sb, "%s\n", t)
+		}
+	}
+	return sb.String(), nil
+}
+
+// upstreamSubjects returns the subjects of the upstream commits since the
+// last sync, newest first. Without a previous sync nothing is listed.
+func (p *patient) upstreamSubjects(head string) ([]string, bool, error) {
+	if p.lastSynced == "" || p.lastSynced == head {
+		return nil, false, nil
+	}
+	iter, err := p.upstreamRepo.Log(&git.LogOptions{From: plumbing.NewHash(head)})
+	if err != nil {
+		return nil, false, err
+	}
+	var subjects []string
+	var more bool
+	err = iter.ForEach(func(c *object.Commit) error {
+		if c.Hash.String() == p.lastSynced {
+			return storer.ErrStop
+		}
+		if len(subjects) == maxCommitSubjects {
+			more = true
+			return storer.ErrStop
+		}
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		subjects = append(subjects, shortHash(c.Hash.String())+" "+subject)
+		return nil
+	})
+	// a shallow mirror may not reach the last synced commit
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, false, err
+	}
+	return subjects, more, nil
+}
+
+// shortHash abbreviates a commit hash to 7 characters. Hashes read from a
+// hand-edited lock file may be shorter.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
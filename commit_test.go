@@ -0,0 +1,75 @@
+package surgeon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitLockOnly(t *testing.T) {
+	upstream, _ := newUpstream(t, map[string]string{
+		"install.sh": "curl https://upstream/raw/main/install.sh\n",
+		"README.md":  "upstream\n",
+	})
+	// the fork pulls from its own origin, not from upstream
+	origin := t.TempDir()
+	_, err := git.PlainClone(origin, true, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	fork := t.TempDir()
+	_, err = git.PlainClone(fork, false, &git.CloneOptions{URL: origin})
+	require.NoError(t, err)
+
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{Mod: "sed", Match: []string{"*.sh"}, Args: []string{"upstream", "fork"}}},
+		Commit:   Commit{Enabled: true, AuthorName: "Fork", AuthorEmail: "fork@example.com"},
+	}
+	opts := Options{
+		ForkRoot: fork,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      io.Discard,
+	}
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Committed)
+
+	// upstream changes a file the fork keeps its own copy of, so only the
+	// lock file changes
+	commit := commitFiles(t, upstream, map[string]string{"README.md": "upstream two\n"})
+	report, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.Copied)
+	assert.NotEmpty(t, report.Committed, "the new lock file is committed")
+	clean, err := newPatient(Config{}, opts).isClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+	l, err := ReadLock(fork)
+	require.NoError(t, err)
+	assert.Equal(t, commit, l.Commit)
+
+	report, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.Committed, "nothing changed")
+}
+
+func TestCommitMessageShortLock(t *testing.T) {
+	upstream, commit := newUpstream(t, map[string]string{"README.md": "upstream\n"})
+	r, err := git.PlainOpen(upstream)
+	require.NoError(t, err)
+	p := newPatient(Config{Upstream: upstream}, Options{ForkRoot: t.TempDir(), Out: &bytes.Buffer{}})
+	p.upstreamRepo = r
+	require.NoError(t, p.resolveUpstream(""))
+
+	// a hand-edited lock file may hold an abbreviated commit
+	p.lastSynced = "abc"
+	msg, err := p.commitMessage()
+	require.NoError(t, err)
+	assert.Contains(t, msg, "Upstream: "+upstream+" abc.."+commit[:7]+"\n")
+}
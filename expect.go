@@ -0,0 +1,44 @@
+package surgeon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bketelsen/surgeon/codemods"
+)
+
+// validateExpect checks the expect settings of a codemod
+func validateExpect(e Expect) error {
+	switch e.Changes {
+	case "", ChangesRequired, ChangesOptional:
+	default:
+		return fmt.Errorf("expect.changes must be %s or %s, not %q", ChangesRequired, ChangesOptional, e.Changes)
+	}
+	if e.MinFiles < 0 || e.MaxFiles < 0 {
+		return errors.New("expect.min_files and expect.max_files cannot be negative")
+	}
+	if e.MaxFiles > 0 && e.MaxFiles < e.MinFiles {
+		return fmt.Errorf("expect.max_files %d is less than expect.min_files %d", e.MaxFiles, e.MinFiles)
+	}
+	return nil
+}
+
+// checkResult compares what a codemod did with its expect settings. A
+// codemod without them that matches or changes nothing gets a warning,
+// which is logged with the other warnings of the codemod.
+func checkResult(mod CodeMod, res *codemods.Result) error {
+	e := mod.Expect
+	switch {
+	case res.Matched < e.MinFiles:
+		return fmt.Errorf("matched %d files, expected at least %d", res.Matched, e.MinFiles)
+	case e.MaxFiles > 0 && res.Matched > e.MaxFiles:
+		return fmt.Errorf("matched %d files, expected at most %d", res.Matched, e.MaxFiles)
+	case res.Changed == 0 && e.Changes == ChangesRequired:
+		return fmt.Errorf("matched %d files but changed none", res.Matched)
+	case res.Matched == 0 && e.Changes == "":
+		res.Warn("matched no files")
+	case res.Changed == 0 && e.Changes == "":
+		res.Warn("changed none of the %d matched files", res.Matched)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package surgeon
+
+import (
+	"testing"
+
+	"github.com/bketelsen/surgeon/codemods"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		expect  Expect
+		result  codemods.Result
+		wantErr bool
+	}{
+		{
+			name:   "No expectations and no matches",
+			result: codemods.Result{},
+		},
+		{
+			name:    "Changes required",
+			expect:  Expect{Changes: ChangesRequired},
+			result:  codemods.Result{Matched: 3},
+			wantErr: true,
+		},
+		{
+			name:   "Changes optional",
+			expect: Expect{Changes: ChangesOptional},
+			result: codemods.Result{Matched: 3},
+		},
+		{
+			name:    "Too few files",
+			expect:  Expect{MinFiles: 2},
+			result:  codemods.Result{Matched: 1, Changed: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Too many files",
+			expect:  Expect{MaxFiles: 2},
+			result:  codemods.Result{Matched: 3, Changed: 3},
+			wantErr: true,
+		},
+		{
+			name:   "Within limits",
+			expect: Expect{Changes: ChangesRequired, MinFiles: 1, MaxFiles: 2},
+			result: codemods.Result{Matched: 2, Changed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResult(CodeMod{Mod: "sed", Expect: tt.expect}, &tt.result)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateExpect(t *testing.T) {
+	assert.NoError(t, validateExpect(Expect{}))
+	assert.NoError(t, validateExpect(Expect{Changes: ChangesOptional, MinFiles: 1, MaxFiles: 1}))
+	assert.Error(t, validateExpect(Expect{Changes: "always"}))
+	assert.Error(t, validateExpect(Expect{MinFiles: -1}))
+	assert.Error(t, validateExpect(Expect{MinFiles: 3, MaxFiles: 2}))
+}
@@ -0,0 +1,72 @@
+package surgeon
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ignoreFile holds additional gitignore patterns in the root of the fork
+const ignoreFile = ".surgeonignore"
+
+// loadIgnore builds the matcher for the gitignore patterns of the ignore
+// list followed by those in .surgeonignore. Later patterns take precedence,
+// so the ignore file can re-include paths the configuration ignores.
+func (p *patient) loadIgnore() error {
+	var patterns []gitignore.Pattern
+	for _, i := range p.Config.IgnoreList {
+		if i.Pattern != "" {
+			patterns = append(patterns, gitignore.ParsePattern(i.Pattern, nil))
+		}
+	}
+
+	bb, err := os.ReadFile(filepath.Join(p.ForkRoot, ignoreFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	patterns = append(patterns, parseIgnore(bb)...)
+
+	if len(patterns) > 0 {
+		p.ignore = gitignore.NewMatcher(patterns)
+	}
+	return nil
+}
+
+// parseIgnore parses gitignore patterns, skipping blank lines and comments
+func parseIgnore(content []byte) []gitignore.Pattern {
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return patterns
+}
+
+// isIgnored reports whether path, relative to the repository root, is
+// excluded from the sync by a prefix or a gitignore pattern
+func (p *patient) isIgnored(path string) bool {
+	for _, i := range p.Config.IgnoreList {
+		if i.Prefix == "" {
+			continue
+		}
+		p.log.Debug("Checking Ignore List", "path", path, "prefix", i.Prefix)
+		if strings.HasPrefix(path, i.Prefix) {
+			p.log.Debug("Ignoring", "path", path)
+			return true
+		}
+	}
+	if p.ignore != nil && p.ignore.Match(strings.Split(filepath.ToSlash(path), "/"), false) {
+		p.log.Debug("Ignoring", "path", path)
+		return true
+	}
+	return false
+}
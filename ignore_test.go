@@ -0,0 +1,75 @@
+package surgeon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsIgnored(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignoreList []Ignore
+		ignoreFile string
+		ignored    []string
+		kept       []string
+	}{
+		{
+			name:       "Prefix",
+			ignoreList: []Ignore{{Prefix: "ct"}},
+			ignored:    []string{"ct/app.sh", "ctl/run.sh"},
+			kept:       []string{"misc/ct.sh"},
+		},
+		{
+			name:       "Directory pattern",
+			ignoreList: []Ignore{{Pattern: "ct/"}},
+			ignored:    []string{"ct/app.sh", "ct/sub/app.sh", "misc/ct/app.sh"},
+			kept:       []string{"ctl/run.sh", "misc/ct"},
+		},
+		{
+			name:       "Anchored directory pattern",
+			ignoreList: []Ignore{{Pattern: "/ct/"}},
+			ignored:    []string{"ct/app.sh"},
+			kept:       []string{"misc/ct/app.sh"},
+		},
+		{
+			name:       "Globs",
+			ignoreList: []Ignore{{Pattern: "*.md"}, {Pattern: "docs/**/*.png"}},
+			ignored:    []string{"README.md", "docs/guide/intro.md", "docs/img/a/logo.png"},
+			kept:       []string{"logo.png", "misc/app.sh"},
+		},
+		{
+			name:       "Negation",
+			ignoreList: []Ignore{{Pattern: "install/"}, {Pattern: "!install/common.sh"}},
+			ignored:    []string{"install/app.sh"},
+			kept:       []string{"install/common.sh"},
+		},
+		{
+			name:       "Ignore file",
+			ignoreList: []Ignore{{Pattern: "*.sh"}},
+			ignoreFile: "# keep the shared helpers\n\n!misc/*.sh\n.github/\n",
+			ignored:    []string{"ct/app.sh", ".github/workflows/ci.yml"},
+			kept:       []string{"misc/build.sh", "misc/build.func"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.ignoreFile != "" {
+				err := os.WriteFile(filepath.Join(dir, ignoreFile), []byte(tt.ignoreFile), 0o644)
+				require.NoError(t, err)
+			}
+			p := newPatient(Config{IgnoreList: tt.ignoreList}, Options{ForkRoot: dir})
+			require.NoError(t, p.loadIgnore())
+			for _, path := range tt.ignored {
+				assert.True(t, p.isIgnored(path), path)
+			}
+			for _, path := range tt.kept {
+				assert.False(t, p.isIgnored(path), path)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package surgeon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/bketelsen/surgeon/codemods"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// LockFile is the name of the lock file in the root of the fork
+const LockFile = ".surgeon.lock"
+
+// ReadLock reads the lock file from the fork root
+func ReadLock(forkRoot string) (Lock, error) {
+	var l Lock
+	bb, err := os.ReadFile(filepath.Join(forkRoot, LockFile))
+	if err != nil {
+		return l, err
+	}
+	err = yaml.Unmarshal(bb, &l)
+	if err != nil {
+		return l, fmt.Errorf("parsing %s: %w", LockFile, err)
+	}
+	if l.Commit == "" {
+		return l, fmt.Errorf("%s has no commit", LockFile)
+	}
+	return l, nil
+}
+
+// WriteLock writes the lock file to the fork root
+func WriteLock(forkRoot string, l Lock) error {
+	bb, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(forkRoot, LockFile), bb, 0o644)
+}
+
+// upstreamRevision returns the revision of upstream to sync: the locked
+// commit with --locked, otherwise upstream_ref
+func (p *patient) upstreamRevision() (string, error) {
+	if !p.Locked {
+		return p.Config.UpstreamRef, nil
+	}
+	l, err := ReadLock(p.ForkRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("--locked requires %s, run 'surgeon update' first", LockFile)
+		}
+		return "", err
+	}
+	if l.Upstream != p.Config.Upstream {
+		return "", fmt.Errorf("%s is for upstream %s, not %s", LockFile, l.Upstream, p.Config.Upstream)
+	}
+	p.log.Info("Using locked upstream commit", "commit", l.Commit)
+	return l.Commit, nil
+}
+
+// resolveUpstream resolves rev (a branch, tag or commit) to the upstream
+// commit to sync and opens its files. An empty rev resolves to the default
+// branch.
+func (p *patient) resolveUpstream(rev string) error {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := p.upstreamRepo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return fmt.Errorf("resolving upstream ref %q: %w", rev, err)
+	}
+	// annotated tags resolve to the tag object, not the commit
+	if tag, err := p.upstreamRepo.TagObject(*hash); err == nil {
+		c, err := tag.Commit()
+		if err != nil {
+			return fmt.Errorf("resolving upstream ref %q: %w", rev, err)
+		}
+		hash = &c.Hash
+	}
+
+	p.log.Info("Reading upstream", "ref", rev, "commit", hash.String())
+	p.head, err = p.upstreamRepo.CommitObject(*hash)
+	if err != nil {
+		return fmt.Errorf("reading upstream commit: %w", err)
+	}
+	tree, err := p.head.Tree()
+	if err != nil {
+		return fmt.Errorf("reading upstream tree: %w", err)
+	}
+	p.upstream = codemods.NewTree(codemods.GitFS(tree))
+	return nil
+}
+
+// upstreamCommit returns the upstream commit being synced
+func (p *patient) upstreamCommit() (string, error) {
+	if p.head == nil {
+		return "", errors.New("upstream has not been read")
+	}
+	return p.head.Hash.String(), nil
+}
+
+// currentLock returns the lock for the upstream commit being synced
+func (p *patient) currentLock() (Lock, error) {
+	commit, err := p.upstreamCommit()
+	if err != nil {
+		return Lock{}, err
+	}
+	return Lock{Upstream: p.Config.Upstream, Ref: p.Config.UpstreamRef, Commit: commit}, nil
+}
+
+// lock records the current upstream commit in the lock file, along with
+// the codemods applied to it and the conflicts left to resolve
+func (p *patient) lock() error {
+	l, err := p.currentLock()
+	if err != nil {
+		return err
+	}
+	mods, err := p.codeModsHash(l.Commit)
+	if err != nil {
+		return err
+	}
+	l.Synced = &Synced{Commit: l.Commit, CodeMods: mods}
+	for _, path := range p.conflicted {
+		content, _, err := readForkFile(p.ForkRoot, path)
+		if err != nil {
+			return err
+		}
+		if l.Synced.Conflicts == nil {
+			l.Synced.Conflicts = map[string]string{}
+		}
+		l.Synced.Conflicts[path] = contentHash(content)
+	}
+	old, err := ReadLock(p.ForkRoot)
+	p.lockChanged = err != nil || !reflect.DeepEqual(old, l)
+	p.log.Info("Writing lock file", "commit", l.Commit)
+	return WriteLock(p.ForkRoot, l)
+}
+
+// codeModsHash returns a hash of the codemods selected for the run, with
+// their templates expanded for the upstream commit, to tell whether the
+// last sync applied the same ones
+func (p *patient) codeModsHash(commit string) (string, error) {
+	data := p.templateData(commit)
+	var mods []CodeMod
+	for _, mod := range p.Config.CodeMods {
+		if !p.selected(mod) {
+			continue
+		}
+		mod, problems := expandCodeMod(mod, data)
+		if len(problems) > 0 {
+			return "", problems[0].err
+		}
+		mods = append(mods, CodeMod{Mod: mod.Mod, Match: mod.Match, Args: mod.Args, With: mod.With})
+	}
+	bb, err := json.Marshal(mods)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bb)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Update resolves the upstream_ref of c to its current commit and records
+// it in the lock file of the fork in opts.ForkRoot without changing the
+// fork. It returns the new lock.
+func Update(ctx context.Context, c Config, opts Options) (Lock, error) {
+	opts.Locked = false
+	return newPatient(c, opts).update(ctx)
+}
+
+func (p *patient) update(ctx context.Context) (Lock, error) {
+	old, err := ReadLock(p.ForkRoot)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Lock{}, err
+	}
+	err = p.clone(ctx)
+	if err != nil {
+		return Lock{}, fmt.Errorf("cloning upstream repository: %w", err)
+	}
+
+	l, err := p.currentLock()
+	if err != nil {
+		return l, err
+	}
+	// the fork stays synced with the commit it was synced with
+	if old.Upstream == l.Upstream {
+		l.Synced = old.Synced
+	}
+	if l.Commit == old.Commit && old.Upstream == l.Upstream {
+		_, _ = fmt.Fprintf(p.Out, "%s is up to date at %s\n", LockFile, l.Commit)
+		return l, nil
+	}
+	p.log.Info("Writing lock file", "commit", l.Commit)
+	err = WriteLock(p.ForkRoot, l)
+	if err != nil {
+		return l, err
+	}
+	if old.Commit == "" {
+		_, _ = fmt.Fprintf(p.Out, "Locked upstream at %s\n", l.Commit)
+	} else {
+		_, _ = fmt.Fprintf(p.Out, "Updated upstream from %s to %s\n", old.Commit, l.Commit)
+	}
+	return l, nil
+}
@@ -0,0 +1,154 @@
+package surgeon
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// mergeHunk replaces the base lines [start, end) with lines
+type mergeHunk struct {
+	start, end int
+	lines      []string
+}
+
+// splitLines splits s into lines that keep their trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineHunks returns the changes that turn base into other
+func lineHunks(base, other string) []mergeHunk {
+	var hunks []mergeHunk
+	var cur *mergeHunk
+	o := 0
+	for _, d := range diff.Do(base, other) {
+		lines := splitLines(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if cur != nil {
+				hunks = append(hunks, *cur)
+				cur = nil
+			}
+			o += len(lines)
+		case diffmatchpatch.DiffDelete:
+			if cur == nil {
+				cur = &mergeHunk{start: o, end: o}
+			}
+			cur.end += len(lines)
+			o += len(lines)
+		case diffmatchpatch.DiffInsert:
+			if cur == nil {
+				cur = &mergeHunk{start: o, end: o}
+			}
+			cur.lines = append(cur.lines, lines...)
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	return hunks
+}
+
+// applyLineHunks renders base[start:end) with the given hunks applied
+func applyLineHunks(base []string, hunks []mergeHunk, start, end int) []string {
+	var out []string
+	pos := start
+	for _, h := range hunks {
+		out = append(out, base[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+// threeWayMerge merges the changes from base to ours and from base to
+// theirs, line by line. Changes that overlap or touch and differ are
+// written between conflict markers, and the returned bool reports whether
+// any conflicts were found.
+func threeWayMerge(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, bool) {
+	baseLines := splitLines(string(base))
+	hOurs := lineHunks(string(base), string(ours))
+	hTheirs := lineHunks(string(base), string(theirs))
+
+	var out []string
+	var conflict bool
+	pos, i, j := 0, 0, 0
+	for i < len(hOurs) || j < len(hTheirs) {
+		// start a group with the earliest hunk and extend it with every
+		// hunk from either side that overlaps or touches it
+		var start int
+		switch {
+		case j >= len(hTheirs) || (i < len(hOurs) && hOurs[i].start <= hTheirs[j].start):
+			start = hOurs[i].start
+		default:
+			start = hTheirs[j].start
+		}
+		end := start
+		var a, b []mergeHunk
+		for {
+			extended := false
+			if i < len(hOurs) && hOurs[i].start <= end {
+				a = append(a, hOurs[i])
+				end = max(end, hOurs[i].end)
+				i++
+				extended = true
+			}
+			if j < len(hTheirs) && hTheirs[j].start <= end {
+				b = append(b, hTheirs[j])
+				end = max(end, hTheirs[j].end)
+				j++
+				extended = true
+			}
+			if !extended {
+				break
+			}
+		}
+
+		out = append(out, baseLines[pos:start]...)
+		ra := applyLineHunks(baseLines, a, start, end)
+		rb := applyLineHunks(baseLines, b, start, end)
+		switch {
+		case len(b) == 0:
+			out = append(out, ra...)
+		case len(a) == 0:
+			out = append(out, rb...)
+		case strings.Join(ra, "") == strings.Join(rb, ""):
+			out = append(out, ra...)
+		default:
+			conflict = true
+			out = append(out, "<<<<<<< "+oursLabel+"\n")
+			out = appendTerminated(out, ra)
+			out = append(out, "=======\n")
+			out = appendTerminated(out, rb)
+			out = append(out, ">>>>>>> "+theirsLabel+"\n")
+		}
+		pos = end
+	}
+	out = append(out, baseLines[pos:]...)
+	return []byte(strings.Join(out, "")), conflict
+}
+
+// appendTerminated appends lines, making sure the last one ends in a newline
+// so a following conflict marker starts on its own line
+func appendTerminated(out, lines []string) []string {
+	out = append(out, lines...)
+	if n := len(out); n > 0 && !strings.HasSuffix(out[n-1], "\n") {
+		out[n-1] += "\n"
+	}
+	return out
+}
+
+// isBinary reports whether content looks like a binary file
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) != -1
+}
@@ -0,0 +1,72 @@
+package surgeon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		ours     string
+		theirs   string
+		expected string
+		conflict bool
+	}{
+		{
+			name:     "Changes in different places",
+			base:     "one\ntwo\nthree\nfour\nfive\n",
+			ours:     "ONE\ntwo\nthree\nfour\nfive\n",
+			theirs:   "one\ntwo\nthree\nfour\nFIVE\n",
+			expected: "ONE\ntwo\nthree\nfour\nFIVE\n",
+		},
+		{
+			name:     "Same change on both sides",
+			base:     "one\ntwo\nthree\n",
+			ours:     "one\nTWO\nthree\n",
+			theirs:   "one\nTWO\nthree\n",
+			expected: "one\nTWO\nthree\n",
+		},
+		{
+			name:     "Insertions in different places",
+			base:     "one\ntwo\nthree\nfour\n",
+			ours:     "zero\none\ntwo\nthree\nfour\n",
+			theirs:   "one\ntwo\nthree\nfour\nfive\n",
+			expected: "zero\none\ntwo\nthree\nfour\nfive\n",
+		},
+		{
+			name:     "Conflicting change",
+			base:     "one\ntwo\nthree\n",
+			ours:     "one\nfork\nthree\n",
+			theirs:   "one\nupstream\nthree\n",
+			expected: "one\n<<<<<<< fork\nfork\n=======\nupstream\n>>>>>>> upstream\nthree\n",
+			conflict: true,
+		},
+		{
+			name:     "Conflict without trailing newline",
+			base:     "one\ntwo",
+			ours:     "one\nfork",
+			theirs:   "one\nupstream",
+			expected: "one\n<<<<<<< fork\nfork\n=======\nupstream\n>>>>>>> upstream\n",
+			conflict: true,
+		},
+		{
+			name:     "Empty base",
+			base:     "",
+			ours:     "fork\n",
+			theirs:   "upstream\n",
+			expected: "<<<<<<< fork\nfork\n=======\nupstream\n>>>>>>> upstream\n",
+			conflict: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, conflict := threeWayMerge([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs), "fork", "upstream")
+			assert.Equal(t, tt.expected, string(result))
+			assert.Equal(t, tt.conflict, conflict)
+		})
+	}
+}
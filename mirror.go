@@ -0,0 +1,141 @@
+package surgeon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage"
+)
+
+// mirrorRefSpecs fetches branches and tags, but not pull request refs
+var mirrorRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Source provides the upstream repository
+type Source interface {
+	// Storage returns the storage holding the commits of the repository
+	// at url, fetching them if needed
+	Storage(ctx context.Context, url string) (storage.Storer, error)
+}
+
+// Mirror is the default Source. It keeps a bare mirror of each upstream
+// repository in a cache directory and fetches new commits into it.
+type Mirror struct {
+	Dir     string       // holds the mirrors, surgeon/mirrors in the user cache directory if empty
+	Offline bool         // use the mirror as is without fetching
+	Depth   int          // limit fetches to this many commits, 0 for full history
+	Logger  *slog.Logger // slog.Default() if nil
+}
+
+// assert that Mirror implements Source
+var _ Source = &Mirror{}
+
+func (m *Mirror) log() *slog.Logger {
+	if m.Logger == nil {
+		return slog.Default()
+	}
+	return m.Logger
+}
+
+// dir returns the directory holding the bare mirror of url
+func (m *Mirror) dir(url string) (string, error) {
+	root := m.Dir
+	if root == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("finding cache directory: %w", err)
+		}
+		root = filepath.Join(cache, appName, "mirrors")
+	}
+	sum := sha256.Sum256([]byte(url))
+	name := unsafePathChars.ReplaceAllString(strings.TrimSuffix(path.Base(url), ".git"), "_")
+	return filepath.Join(root, name+"-"+hex.EncodeToString(sum[:8])), nil
+}
+
+// Storage creates or updates the bare mirror of url and returns its
+// storage. With Offline set the mirror is used as is.
+func (m *Mirror) Storage(ctx context.Context, url string) (storage.Storer, error) {
+	dir, err := m.dir(url)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := git.PlainOpen(dir)
+	switch {
+	case err == nil:
+		if m.Offline {
+			m.log().Info("Using cached upstream mirror", "location", dir)
+			return r.Storer, nil
+		}
+		m.log().Info("Fetching upstream mirror", "location", dir)
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		if m.Offline {
+			return nil, fmt.Errorf("no cached mirror of %s, run once without --offline", url)
+		}
+		m.log().Info("Creating upstream mirror", "location", dir)
+		r, err = git.PlainInit(dir, true)
+		if err != nil {
+			return nil, fmt.Errorf("creating mirror: %w", err)
+		}
+		_, err = r.CreateRemote(&config.RemoteConfig{
+			Name:  git.DefaultRemoteName,
+			URLs:  []string{url},
+			Fetch: mirrorRefSpecs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating mirror: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("opening mirror: %w", err)
+	}
+
+	err = m.fetch(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	return r.Storer, nil
+}
+
+// fetch fetches new upstream commits into the mirror and points its
+// HEAD at the upstream default branch
+func (m *Mirror) fetch(ctx context.Context, r *git.Repository) error {
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return err
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: mirrorRefSpecs,
+		Depth:    m.Depth,
+		Tags:     git.NoTags,
+		Force:    true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			return r.Storer.SetReference(ref)
+		}
+	}
+	return nil
+}
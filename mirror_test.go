@@ -0,0 +1,96 @@
+package surgeon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorOffline(t *testing.T) {
+	upstream, cached := newUpstream(t, map[string]string{"install.sh": "echo upstream 1\n"})
+	fork := t.TempDir()
+	_, err := git.PlainClone(fork, false, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	c := Config{Upstream: upstream}
+	mirror := &Mirror{Dir: t.TempDir(), Offline: true}
+	opts := Options{
+		ForkRoot: fork,
+		DryRun:   true,
+		Source:   mirror,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      io.Discard,
+	}
+
+	_, err = Run(context.Background(), c, opts)
+	assert.ErrorContains(t, err, "no cached mirror")
+
+	mirror.Offline = false
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, cached, report.Commit)
+
+	// offline runs use the cached mirror without fetching new commits
+	head := commitFiles(t, upstream, map[string]string{"install.sh": "echo upstream 2\n"})
+	mirror.Offline = true
+	report, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, cached, report.Commit)
+
+	mirror.Offline = false
+	report, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, head, report.Commit)
+}
+
+func TestMirrorDepth(t *testing.T) {
+	upstream, synced := newUpstream(t, map[string]string{
+		"f.sh":    "echo upstream a\n",
+		"gone.sh": "echo gone\n",
+	})
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{Mod: "sed", Match: []string{"*.sh"}, Args: []string{"upstream", "fork"}}},
+		Commit:   Commit{Enabled: true, AuthorName: "Fork", AuthorEmail: "fork@example.com"},
+	}
+	opts := newSyncedFork(t, c, synced, map[string]string{
+		"f.sh":    "echo fork a\n",
+		"gone.sh": "echo gone\n",
+	})
+	mirror := &Mirror{Dir: t.TempDir(), Depth: 1}
+	opts.Source = mirror
+	commitFiles(t, upstream, nil, "gone.sh")
+	head := commitFiles(t, upstream, map[string]string{"f.sh": "echo upstream b\n"})
+
+	// the lock commit lies beyond the shallow boundary of the mirror
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, head, report.Commit)
+	if entries, _ := os.ReadDir(mirror.Dir); assert.Len(t, entries, 1) {
+		_, err = os.Stat(filepath.Join(mirror.Dir, entries[0].Name(), "shallow"))
+		require.NoError(t, err, "the mirror is shallow")
+	}
+
+	// without the last synced commit upstream changes overwrite the fork
+	assert.Equal(t, "echo fork b\n", readForkString(t, opts, "f.sh"))
+	// and the history no longer tells that upstream deleted the file
+	assert.Empty(t, report.Deleted)
+	assert.Equal(t, "echo gone\n", readForkString(t, opts, "gone.sh"))
+
+	// the commit message lists the upstream commits the mirror has
+	r, err := git.PlainOpen(opts.ForkRoot)
+	require.NoError(t, err)
+	ref, err := r.Head()
+	require.NoError(t, err)
+	commit, err := r.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, report.Committed, commit.Hash.String())
+	assert.Contains(t, commit.Message, shortHash(synced)+".."+shortHash(head))
+	assert.Contains(t, commit.Message, shortHash(head)+" Change files")
+}
@@ -0,0 +1,418 @@
+package surgeon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bketelsen/surgeon/codemods"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// patient is the state of one run of the surgeon
+type patient struct {
+	Options
+	Config       Config
+	log          *slog.Logger
+	forkRepo     *git.Repository
+	upstreamRepo *git.Repository
+	head         *object.Commit // upstream commit being synced
+	upstream     *codemods.Tree // files of head, changed by the codemods
+	base         *codemods.Tree // files of the last sync, nil if unknown
+	ignore       gitignore.Matcher
+	lastSynced   string            // upstream commit of the previous sync
+	unresolved   map[string]string // conflicts of the previous sync, by content hash
+	lockChanged  bool              // the sync rewrote the lock file
+	plan         map[string]struct{}
+	removed      []removal
+	copied       []string
+	deleted      []string
+	conflicted   []string
+	results      []ModResult
+	report       *Report // set once the codemods ran
+}
+
+// newPatient returns a run of the surgeon with the defaults for the
+// options left empty
+func newPatient(config Config, opts Options) *patient {
+	if opts.ForkRoot == "" {
+		opts.ForkRoot, _ = os.Getwd()
+	}
+	if opts.Conflicts == "" {
+		opts.Conflicts = ConflictRefuse
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	if opts.Source == nil {
+		opts.Source = &Mirror{Logger: opts.Logger}
+	}
+	return &patient{
+		Options: opts,
+		Config:  config,
+		log:     opts.Logger,
+		plan:    map[string]struct{}{},
+	}
+}
+
+// operate syncs the fork with upstream. Cancelling ctx stops the codemods.
+func (p *patient) operate(ctx context.Context) error {
+	if p.Conflicts != ConflictRefuse && p.Conflicts != ConflictMarkers {
+		return fmt.Errorf("unknown conflict mode %q", p.Conflicts)
+	}
+	p.log.Debug("Opening fork git repository", "path", p.ForkRoot)
+	r, err := git.PlainOpen(p.ForkRoot)
+	if err != nil {
+		p.log.Error("opening git repository", "error", err)
+		return fmt.Errorf("opening git repository: %w", err)
+	}
+	err = p.sanityCheck()
+	if err != nil {
+		p.log.Error("sanity check failed", "error", err)
+		return fmt.Errorf("sanity check failed: %w", err)
+	}
+	p.forkRepo = r
+
+	err = p.loadIgnore()
+	if err != nil {
+		p.log.Error("reading ignore patterns", "error", err)
+		return fmt.Errorf("reading ignore patterns: %w", err)
+	}
+
+	// update the local fork
+	if p.DryRun {
+		p.log.Info("Dry run, not updating local fork")
+	} else {
+		p.log.Debug("Updating local fork")
+		err = p.updateLocalFork(ctx)
+		if err != nil {
+			p.log.Error("updating local fork", "error", err)
+			return fmt.Errorf("updating local fork: %w", err)
+		}
+	}
+
+	err = p.checkConfig()
+	if err != nil {
+		p.log.Error("invalid codemods", "error", err)
+		return fmt.Errorf("invalid codemods: %w", err)
+	}
+
+	// clone the upstream repository
+	p.log.Debug("Cloning upstream repository")
+	err = p.clone(ctx)
+	if err != nil {
+		p.log.Error("cloning upstream repository", "error", err)
+		return fmt.Errorf("cloning upstream repository: %w", err)
+	}
+
+	p.log.Debug("Preparing last synced upstream")
+	err = p.prepareBase(ctx)
+	if err != nil {
+		p.log.Error("preparing last synced upstream", "error", err)
+		return fmt.Errorf("preparing last synced upstream: %w", err)
+	}
+
+	p.log.Debug("Applying code mods")
+	commit, err := p.upstreamCommit()
+	if err != nil {
+		p.log.Error("reading upstream commit", "error", err)
+		return fmt.Errorf("reading upstream commit: %w", err)
+	}
+	p.results, err = p.applyMods(ctx, p.upstream, commit)
+	p.report = &Report{Upstream: p.Config.Upstream, Commit: commit, CodeMods: p.results}
+	if p.report.CodeMods == nil {
+		p.report.CodeMods = []ModResult{}
+	}
+	if err != nil {
+		return err
+	}
+
+	p.log.Info("Comparing directories")
+	missing, err := compareDirs(p.upstream, p.ForkRoot)
+	if err != nil {
+		p.log.Error("comparing directories", "error", err)
+		return fmt.Errorf("comparing directories: %w", err)
+	}
+	if len(missing) > 0 {
+		p.log.Info("Found potential missing files", "count", len(missing))
+		for _, m := range missing {
+			if !strings.HasPrefix(m, ".git") {
+				p.log.Debug("Testing file", "file", m)
+				if !p.isIgnored(m) {
+					p.transplant(m)
+				} else {
+					p.log.Info("Skipping ignored", "file", m)
+				}
+			}
+		}
+	}
+
+	// get the upstream files changed by the codemods
+	p.log.Debug("Getting files changed by codemods")
+	for _, s := range p.upstream.Changed() {
+		// copy the file from the upstream repository to the fork
+		if !p.isIgnored(s) {
+			p.transplant(s)
+		}
+	}
+
+	// conflicts of the last sync are reported until they are resolved
+	for path := range p.unresolved {
+		if !p.isIgnored(path) {
+			p.transplant(path)
+		}
+	}
+
+	p.log.Info("Looking for files removed upstream")
+	removed, err := p.findRemoved()
+	if err != nil {
+		p.log.Error("finding removed files", "error", err)
+		return fmt.Errorf("finding removed files: %w", err)
+	}
+	for _, r := range removed {
+		p.prune(r)
+	}
+
+	changes, err := p.resolve()
+	if err != nil {
+		p.log.Error("resolving changes", "error", err)
+		return fmt.Errorf("resolving changes: %w", err)
+	}
+	if p.DryRun {
+		return p.printPlan(p.Out, changes)
+	}
+	if c := conflicts(changes); len(c) > 0 && p.Conflicts == ConflictRefuse {
+		p.log.Error("fork changes conflict with upstream", "files", c)
+		return fmt.Errorf("fork changes conflict with upstream in %s, resolve them or use --conflicts=markers", strings.Join(c, ", "))
+	}
+	err = p.apply(changes)
+	if err != nil {
+		p.log.Error("applying changes", "error", err)
+		return fmt.Errorf("applying changes: %w", err)
+	}
+	p.report.Copied, p.report.Deleted, p.report.Conflicted = p.copied, p.deleted, p.conflicted
+
+	err = p.lock()
+	if err != nil {
+		p.log.Error("writing lock file", "error", err)
+		return fmt.Errorf("writing lock file: %w", err)
+	}
+	p.printSummary(p.Out)
+	p.printModResults(p.Out)
+
+	if p.Config.Commit.Enabled {
+		p.log.Debug("Committing changes")
+		err = p.commit()
+		if err != nil {
+			p.log.Error("committing changes", "error", err)
+			return fmt.Errorf("committing changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyMods validates and applies every configured codemod to tree, the
+// upstream files at commit, and returns what each of them did. Each
+// codemod sees the changes of the ones before it. A failing codemod
+// leaves the changes made so far in tree.
+func (p *patient) applyMods(ctx context.Context, tree *codemods.Tree, commit string) ([]ModResult, error) {
+	var results []ModResult
+	ctx = codemods.WithLogger(ctx, p.log)
+	fork := codemods.DirFS(p.ForkRoot)
+	data := p.templateData(commit)
+	for _, mod := range p.Config.CodeMods {
+		if !p.selected(mod) {
+			p.log.Info("Skipping codemod", "mod", mod.Mod, "id", mod.ID, "description", mod.Description)
+			continue
+		}
+		mod, problems := expandCodeMod(mod, data)
+		if len(problems) > 0 {
+			p.log.Error("expanding code mod templates", "error", problems[0].err)
+			return results, fmt.Errorf("expanding code mod templates: %w", problems[0].err)
+		}
+		cm, ok := codemods.Mods[mod.Mod]
+		if !ok {
+			p.log.Error("code mod not found", "mod", mod.Mod)
+			return results, fmt.Errorf("code mod %s not found", mod.Mod)
+		}
+		p.log.Info("Applying codemod", "mod", mod.Mod, "description", mod.Description)
+		p.log.Debug("Validating code mod")
+		err := codemods.ValidateMatch(mod.Match)
+		if err != nil {
+			p.log.Error("validating code mod", "error", err)
+			return results, fmt.Errorf("validating code mod: %w", err)
+		}
+		err = validateExpect(mod.Expect)
+		if err != nil {
+			p.log.Error("validating code mod", "error", err)
+			return results, fmt.Errorf("validating code mod: %w", err)
+		}
+		args, err := codemods.ResolveArgs(cm.Args(), mod.Args, mod.With)
+		if err != nil {
+			p.log.Error("validating code mod", "error", err)
+			return results, fmt.Errorf("validating code mod: %w", err)
+		}
+		err = cm.Validate(fork, mod.Match, args...)
+		if err != nil {
+			p.log.Error("validating code mod", "error", err)
+			return results, fmt.Errorf("validating code mod: %w", err)
+		}
+
+		p.log.Debug("Applying code mod")
+		modCtx, cancel := ctx, context.CancelFunc(func() {})
+		if mod.Timeout > 0 {
+			modCtx, cancel = context.WithTimeout(ctx, mod.Timeout)
+		}
+		start := time.Now()
+		res, err := cm.Apply(modCtx, tree, fork, mod.Match, args...)
+		cancel()
+		r := ModResult{
+			ID:          mod.ID,
+			Mod:         mod.Mod,
+			Description: mod.Description,
+			Seconds:     time.Since(start).Seconds(),
+			Result:      res,
+		}
+		if err != nil {
+			r.Error = err.Error()
+			results = append(results, r)
+			p.log.Error("applying code mod", "error", err)
+			return results, fmt.Errorf("applying code mod: %w", err)
+		}
+		p.log.Info("Applied codemod", "mod", mod.Mod, "matched", res.Matched, "changed", res.Changed, "occurrences", res.Occurrences)
+		err = checkResult(mod, &r.Result)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		for _, warning := range r.Warnings {
+			p.log.Warn("Codemod warning", "mod", mod.Mod, "description", mod.Description, "warning", warning)
+		}
+		results = append(results, r)
+		if err != nil {
+			p.log.Error("unexpected code mod result", "mod", mod.Mod, "description", mod.Description, "error", err)
+			return results, fmt.Errorf("code mod %q: %w", mod.Description, err)
+		}
+	}
+	p.log.Debug("Modified files", "files", tree.Changed())
+	return results, nil
+}
+
+// transplant plans to bring path from the upstream repository to the fork
+func (p *patient) transplant(path string) {
+	p.log.Debug("Planning file", "file", path)
+	p.plan[path] = struct{}{}
+}
+
+// prune plans to remove a file that upstream deleted or renamed from the fork
+func (p *patient) prune(r removal) {
+	if r.RenamedTo != "" {
+		p.log.Info("Upstream renamed file", "from", r.Path, "to", r.RenamedTo)
+	} else {
+		p.log.Info("Upstream removed file", "file", r.Path)
+	}
+	p.removed = append(p.removed, r)
+	p.plan[r.Path] = struct{}{}
+}
+
+func (p *patient) sanityCheck() error {
+	ok, err := p.isClean()
+	if err != nil {
+		return fmt.Errorf("error checking if %s is in clean status: %w", p.ForkRoot, err)
+	}
+	if !ok {
+		return fmt.Errorf("git repo %s is dirty", p.ForkRoot)
+	}
+
+	return nil
+}
+
+// isClean checks if the git repository is clean
+func (p *patient) isClean() (bool, error) {
+	r, err := git.PlainOpen(p.ForkRoot)
+	if err != nil {
+		return false, err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return false, err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return false, err
+	}
+	return status.IsClean(), nil
+}
+
+func (p *patient) updateLocalFork(ctx context.Context) error {
+	w, err := p.forkRepo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.PullContext(ctx, &git.PullOptions{
+		RemoteName: "origin",
+	})
+	if err != nil {
+		if err != git.NoErrAlreadyUpToDate {
+			return err
+		}
+	}
+	return nil
+}
+
+// clone opens the upstream repository from the source and reads the
+// commit to sync. The files are read from the repository as needed, none
+// are checked out.
+func (p *patient) clone(ctx context.Context) error {
+	rev, err := p.upstreamRevision()
+	if err != nil {
+		return err
+	}
+	p.log.Info("Cloning upstream repository ", "url", p.Config.Upstream)
+	s, err := p.Source.Storage(ctx, p.Config.Upstream)
+	if err != nil {
+		return err
+	}
+	p.upstreamRepo, err = git.Open(s, nil)
+	if err != nil {
+		return fmt.Errorf("opening upstream repository: %w", err)
+	}
+	return p.resolveUpstream(rev)
+}
+
+// compareDirs returns a list of files that are missing from the target directory
+// compared to the source files
+func compareDirs(source fs.FS, target string) ([]string, error) {
+	var missing []string
+	err := fs.WalkDir(source, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		_, err = os.Lstat(filepath.Join(target, filepath.FromSlash(path)))
+		if os.IsNotExist(err) {
+			missing = append(missing, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
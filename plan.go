@@ -0,0 +1,235 @@
+package surgeon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// planContextLines is the number of unchanged lines shown around each hunk
+const planContextLines = 3
+
+// printPlan writes the list of new, modified, deleted and renamed files,
+// followed by a unified diff of every change a sync would make to the fork.
+func (p *patient) printPlan(w io.Writer, changes []change) error {
+	renamed := p.renames()
+
+	var added, modified, merged, deleted, moved []string
+	var patches []fdiff.FilePatch
+	for _, c := range changes {
+		fp, err := newPlanFilePatch(c, p.ForkRoot)
+		if err != nil {
+			return fmt.Errorf("diffing %s: %w", c.path, err)
+		}
+		switch {
+		case c.conflict:
+		case fp == nil:
+			continue
+		case fp.from == nil:
+			added = append(added, c.path)
+		case fp.to == nil && renamed[c.path] != "":
+			moved = append(moved, c.path+" -> "+renamed[c.path])
+		case fp.to == nil:
+			deleted = append(deleted, c.path)
+		case c.merged:
+			merged = append(merged, c.path)
+		default:
+			modified = append(modified, c.path)
+		}
+		if fp != nil {
+			patches = append(patches, fp)
+		}
+	}
+
+	conflicted := conflicts(changes)
+	if len(patches) == 0 && len(conflicted) == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	printFileList(w, "New files", added)
+	printFileList(w, "Modified files", modified)
+	printFileList(w, "Merged files", merged)
+	printFileList(w, "Deleted files", deleted)
+	printFileList(w, "Renamed files", moved)
+	printFileList(w, "Conflicting files", conflicted)
+	_, _ = fmt.Fprintln(w)
+
+	return fdiff.NewUnifiedEncoder(w, planContextLines).Encode(planPatch(patches))
+}
+
+// printSummary writes the files a sync changed in the fork
+func (p *patient) printSummary(w io.Writer) {
+	renamed := p.renames()
+	var deleted, moved []string
+	for _, r := range p.deleted {
+		if renamed[r] != "" {
+			moved = append(moved, r+" -> "+renamed[r])
+		} else {
+			deleted = append(deleted, r)
+		}
+	}
+	if len(p.copied) == 0 && len(p.deleted) == 0 && len(p.conflicted) == 0 {
+		_, _ = fmt.Fprintln(w, "No changes.")
+		return
+	}
+	printFileList(w, "Updated files", p.copied)
+	printFileList(w, "Deleted files", deleted)
+	printFileList(w, "Renamed files", moved)
+	printFileList(w, "Conflicting files", p.conflicted)
+}
+
+// renames maps the fork files that upstream renamed to their new path
+func (p *patient) renames() map[string]string {
+	renamed := map[string]string{}
+	for _, r := range p.removed {
+		if r.RenamedTo != "" {
+			renamed[r.Path] = r.RenamedTo
+		}
+	}
+	return renamed
+}
+
+func printFileList(w io.Writer, title string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s (%d):\n", title, len(files))
+	for _, f := range files {
+		_, _ = fmt.Fprintf(w, "  %s\n", f)
+	}
+}
+
+// newPlanFilePatch returns a patch transforming the fork version of a
+// file into the planned content. It returns nil if both are identical.
+func newPlanFilePatch(c change, target string) (*planFilePatch, error) {
+	from, err := readPlanFile(c.path, target)
+	if err != nil {
+		return nil, err
+	}
+	var to *planFile
+	if c.content != nil {
+		mode := planMode(c.mode)
+		if mode == filemode.Regular && from != nil && from.mode == filemode.Executable {
+			mode = filemode.Executable
+		}
+		to = newPlanFile(c.path, mode, c.content)
+	}
+	if from != nil && to != nil && bytes.Equal(from.content, to.content) && (from.mode == filemode.Symlink) == (to.mode == filemode.Symlink) {
+		return nil, nil
+	}
+
+	fp := &planFilePatch{from: from, to: to}
+	if (from != nil && isBinary(from.content)) || (to != nil && isBinary(to.content)) {
+		fp.binary = true
+		return fp, nil
+	}
+
+	var src, dst string
+	if from != nil {
+		src = string(from.content)
+	}
+	if to != nil {
+		dst = string(to.content)
+	}
+	for _, d := range diff.Do(src, dst) {
+		var op fdiff.Operation
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			op = fdiff.Equal
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		}
+		fp.chunks = append(fp.chunks, planChunk{content: d.Text, op: op})
+	}
+	return fp, nil
+}
+
+// readPlanFile reads path below root, returning nil if it does not exist.
+func readPlanFile(path, root string) (*planFile, error) {
+	content, mode, err := readForkFile(root, path)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	return newPlanFile(path, planMode(mode), content), nil
+}
+
+// planMode returns the git file mode shown in the plan for a file mode
+func planMode(mode fs.FileMode) filemode.FileMode {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return filemode.Symlink
+	case mode&0o111 != 0:
+		return filemode.Executable
+	}
+	return filemode.Regular
+}
+
+func newPlanFile(path string, mode filemode.FileMode, content []byte) *planFile {
+	return &planFile{
+		path:    filepath.ToSlash(path),
+		mode:    mode,
+		content: content,
+		hash:    plumbing.ComputeHash(plumbing.BlobObject, content),
+	}
+}
+
+// planPatch implements diff.Patch for a dry run
+type planPatch []fdiff.FilePatch
+
+func (p planPatch) FilePatches() []fdiff.FilePatch { return p }
+func (p planPatch) Message() string                { return "" }
+
+// planFilePatch implements diff.FilePatch for a single fork file
+type planFilePatch struct {
+	from, to *planFile
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+func (fp *planFilePatch) IsBinary() bool { return fp.binary }
+
+func (fp *planFilePatch) Files() (fdiff.File, fdiff.File) {
+	// avoid returning typed nil pointers inside the interface
+	var from, to fdiff.File
+	if fp.from != nil {
+		from = fp.from
+	}
+	if fp.to != nil {
+		to = fp.to
+	}
+	return from, to
+}
+
+func (fp *planFilePatch) Chunks() []fdiff.Chunk { return fp.chunks }
+
+// planFile implements diff.File
+type planFile struct {
+	path    string
+	mode    filemode.FileMode
+	content []byte
+	hash    plumbing.Hash
+}
+
+func (f *planFile) Hash() plumbing.Hash     { return f.hash }
+func (f *planFile) Mode() filemode.FileMode { return f.mode }
+func (f *planFile) Path() string            { return f.path }
+
+// planChunk implements diff.Chunk
+type planChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c planChunk) Content() string       { return c.content }
+func (c planChunk) Type() fdiff.Operation { return c.op }
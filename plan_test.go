@@ -0,0 +1,65 @@
+package surgeon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDryRun(t *testing.T) {
+	upstream, synced := newUpstream(t, map[string]string{
+		"install.sh":   "echo upstream one\n",
+		"conflict.txt": "upstream base\n",
+		"gone.sh":      "echo gone\n",
+	})
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{Mod: "sed", Match: []string{"*.sh", "*.txt"}, Args: []string{"upstream", "changed"}}},
+	}
+	fork := t.TempDir()
+	_, err := git.PlainClone(fork, false, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	commitFiles(t, fork, map[string]string{
+		LockFile:       syncedLock(t, c, synced),
+		"install.sh":   "echo changed one\n",
+		"conflict.txt": "fork\n",
+	})
+
+	commitFiles(t, upstream, map[string]string{
+		"install.sh":   "echo upstream two\n",
+		"conflict.txt": "upstream new\n",
+		"new.sh":       "echo new\n",
+	}, "gone.sh")
+
+	var out bytes.Buffer
+	opts := Options{
+		ForkRoot:  fork,
+		DryRun:    true,
+		Conflicts: ConflictMarkers,
+		Source:    &Mirror{Dir: t.TempDir()},
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:       &out,
+	}
+	_, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+
+	plan := out.String()
+	assert.Contains(t, plan, "New files (1):\n  new.sh\n")
+	assert.Contains(t, plan, "Modified files (1):\n  install.sh\n")
+	assert.Contains(t, plan, "Deleted files (1):\n  gone.sh\n")
+	assert.Contains(t, plan, "Conflicting files (1):\n  conflict.txt\n")
+	assert.Contains(t, plan, "-echo changed one\n+echo changed two\n")
+	assert.Contains(t, plan, "+<<<<<<< fork\n")
+	assert.Contains(t, plan, "--- a/gone.sh\n+++ /dev/null\n")
+
+	// a dry run leaves the fork alone
+	clean, err := newPatient(Config{}, opts).isClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+}
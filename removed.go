@@ -0,0 +1,204 @@
+package surgeon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// removal is a fork file that no longer exists upstream
+type removal struct {
+	Path      string
+	RenamedTo string // set if upstream moved the file without changing it
+}
+
+// findRemoved returns the fork files that upstream has deleted or renamed.
+// A file only counts as removed if it exists somewhere in the upstream
+// history, so files that only ever existed in the fork are left alone.
+func (p *patient) findRemoved() ([]removal, error) {
+	candidates, err := forkOnlyFiles(p.upstream, p.ForkRoot)
+	if err != nil {
+		return nil, err
+	}
+	pending := map[string]bool{}
+	for _, c := range candidates {
+		if p.isIgnored(c) || p.isForkOwned(c) {
+			continue
+		}
+		pending[c] = true
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	p.log.Debug("Searching upstream history for fork-only files", "count", len(pending))
+	last, err := p.lastBlobs(pending)
+	if err != nil {
+		return nil, fmt.Errorf("reading upstream history: %w", err)
+	}
+	if len(last) == 0 {
+		return nil, nil
+	}
+
+	// an unchanged blob at a new path is a rename
+	tree, err := p.head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	current := map[plumbing.Hash]string{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := current[entry.Hash]; !ok && entry.Mode.IsFile() {
+			current[entry.Hash] = name
+		}
+	}
+
+	var removed []removal
+	for _, c := range candidates {
+		hash, ok := last[c]
+		if !ok {
+			continue
+		}
+		removed = append(removed, removal{Path: c, RenamedTo: current[hash]})
+	}
+	return removed, nil
+}
+
+// lastBlobs walks the upstream history from the synced commit once and
+// returns the most recent blob of every path in paths that upstream used
+// to have. Only the directories holding the paths are read, and each tree
+// only once, so unchanged directories cost nothing in older commits.
+func (p *patient) lastBlobs(paths map[string]bool) (map[string]plumbing.Hash, error) {
+	dirs := map[string]bool{}
+	for name := range paths {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	last := map[string]plumbing.Hash{}
+	indexed := map[plumbing.Hash]bool{}
+	var index func(tree *object.Tree, dir string) error
+	index = func(tree *object.Tree, dir string) error {
+		if indexed[tree.Hash] {
+			return nil
+		}
+		indexed[tree.Hash] = true
+		for _, e := range tree.Entries {
+			name := path.Join(dir, e.Name)
+			switch {
+			case e.Mode == filemode.Dir && dirs[name]:
+				sub, err := p.upstreamRepo.TreeObject(e.Hash)
+				if err != nil {
+					return err
+				}
+				err = index(sub, name)
+				if err != nil {
+					return err
+				}
+			case e.Mode.IsFile() && paths[name]:
+				if _, ok := last[name]; !ok {
+					last[name] = e.Hash
+				}
+			}
+		}
+		return nil
+	}
+
+	iter, err := p.upstreamRepo.Log(&git.LogOptions{From: p.head.Hash})
+	if err != nil {
+		return nil, err
+	}
+	err = iter.ForEach(func(c *object.Commit) error {
+		tree, err := c.Tree()
+		if err != nil {
+			return err
+		}
+		err = index(tree, "")
+		if err != nil {
+			return err
+		}
+		if len(last) == len(paths) {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	// a shallow mirror ends at a commit whose parents are missing
+	if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, err
+	}
+	return last, nil
+}
+
+// isForkOwned reports whether path belongs to the fork's surgeon setup
+// and must never be removed
+func (p *patient) isForkOwned(path string) bool {
+	if strings.HasPrefix(path, ".surgeon") {
+		return true
+	}
+	modsDir := filepath.ToSlash(filepath.Clean(p.Config.ModsDir))
+	return p.Config.ModsDir != "" && (path == modsDir || strings.HasPrefix(path, modsDir+"/"))
+}
+
+// removeFile deletes path from the fork along with any parent directories
+// left empty
+func (p *patient) removeFile(path string) error {
+	p.log.Debug("Removing file", "file", path)
+	err := os.Remove(filepath.Join(p.ForkRoot, path))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+		if os.Remove(filepath.Join(p.ForkRoot, dir)) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// forkOnlyFiles returns the files below target that do not exist in source
+func forkOnlyFiles(source fs.FS, target string) ([]string, error) {
+	var only []string
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(target, path)
+		if err != nil {
+			return err
+		}
+		_, err = fs.Stat(source, filepath.ToSlash(rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			only = append(only, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return only, nil
+}
@@ -0,0 +1,63 @@
+package surgeon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRemoved(t *testing.T) {
+	upstream, _ := newUpstream(t, map[string]string{
+		"keep.sh":     "echo keep\n",
+		"ct/old.sh":   "echo old\n",
+		"ct/move.sh":  "echo move\n",
+		"ct/edit.sh":  "echo edit\n",
+		"misc/x.func": "x\n",
+	})
+	fork := t.TempDir()
+	_, err := git.PlainClone(fork, false, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	commitFiles(t, fork, map[string]string{"ct/mine.sh": "echo mine\n", ".surgeon.yaml": "upstream: x\n"})
+
+	// upstream deletes one file, moves another unchanged and moves and
+	// edits a third
+	commitFiles(t, upstream, map[string]string{
+		"ct/new/move.sh": "echo move\n",
+		"ct/edited.sh":   "echo edited\n",
+	}, "ct/old.sh", "ct/move.sh", "ct/edit.sh")
+	commitFiles(t, upstream, map[string]string{"misc/x.func": "y\n"})
+
+	p := newPatient(Config{Upstream: upstream}, Options{
+		ForkRoot: fork,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, p.clone(context.Background()))
+	removed, err := p.findRemoved()
+	require.NoError(t, err)
+	assert.Equal(t, []removal{
+		{Path: "ct/edit.sh"},
+		{Path: "ct/move.sh", RenamedTo: "ct/new/move.sh"},
+		{Path: "ct/old.sh"},
+	}, removed)
+
+	// the removals are pruned from the fork, fork-only files are kept
+	for _, r := range removed {
+		p.prune(r)
+	}
+	changes, err := p.resolve()
+	require.NoError(t, err)
+	require.NoError(t, p.apply(changes))
+	assert.Equal(t, []string{"ct/edit.sh", "ct/move.sh", "ct/old.sh"}, p.deleted)
+	assert.NoFileExists(t, filepath.Join(fork, "ct", "old.sh"))
+	assert.FileExists(t, filepath.Join(fork, "ct", "mine.sh"))
+	_, err = os.Stat(filepath.Join(fork, "ct"))
+	assert.NoError(t, err)
+}
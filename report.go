@@ -0,0 +1,45 @@
+package surgeon
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bketelsen/surgeon/codemods"
+)
+
+// ModResult is what one codemod did to the upstream tree
+type ModResult struct {
+	ID          string  `json:"id,omitempty"`
+	Mod         string  `json:"mod"`
+	Description string  `json:"description"`
+	Seconds     float64 `json:"seconds"`
+	Error       string  `json:"error,omitempty"`
+	codemods.Result
+}
+
+// Report is what a run of the surgeon did, the CLI writes it as JSON with
+// --report
+type Report struct {
+	Upstream   string      `json:"upstream"`
+	Commit     string      `json:"commit,omitempty"` // upstream commit the codemods were applied to
+	CodeMods   []ModResult `json:"codemods"`
+	Copied     []string    `json:"copied,omitempty"`     // files written to the fork
+	Deleted    []string    `json:"deleted,omitempty"`    // files removed from the fork
+	Conflicted []string    `json:"conflicted,omitempty"` // files written with conflict markers
+	Committed  string      `json:"committed,omitempty"`  // commit of the synced files in the fork
+}
+
+// printModResults prints a line per codemod describing what it changed
+func (p *patient) printModResults(w io.Writer) {
+	if len(p.results) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "Codemods (%d):\n", len(p.results))
+	for _, r := range p.results {
+		_, _ = fmt.Fprintf(w, "  %s (%s): %d of %d files changed, %d occurrences, %d bytes\n",
+			r.Mod, r.Description, r.Changed, r.Matched, r.Occurrences, r.Bytes)
+		for _, warning := range r.Warnings {
+			_, _ = fmt.Fprintf(w, "    warning: %s\n", warning)
+		}
+	}
+}
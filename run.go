@@ -0,0 +1,36 @@
+package surgeon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// appName names the cache directory of the default Source
+const appName = "surgeon"
+
+// Options control a run of the surgeon. The zero value syncs the fork in
+// the current directory.
+type Options struct {
+	ForkRoot  string       // root of the fork, the current directory if empty
+	DryRun    bool         // print the planned changes instead of writing them
+	Locked    bool         // sync to the upstream commit recorded in the lock file
+	Conflicts string       // how to handle files changed in the fork and upstream, ConflictRefuse if empty
+	Only      []string     // run only the codemods with these ids or names
+	Skip      []string     // skip the codemods with these ids or names
+	Tags      []string     // run only the codemods with one of these tags
+	Source    Source       // provides the upstream repository, a Mirror in the user cache if nil
+	Logger    *slog.Logger // receives progress, slog.Default() if nil
+	Out       io.Writer    // receives the plan, the summary and the codemod results, discarded if nil
+}
+
+// Run syncs the fork in opts.ForkRoot with the upstream repository of c,
+// applying its codemods. Cancelling ctx stops the codemods.
+//
+// The report is returned even if the run fails once the codemods ran, so
+// it tells which codemod failed. It is nil if the run failed before that.
+func Run(ctx context.Context, c Config, opts Options) (*Report, error) {
+	p := newPatient(c, opts)
+	err := p.operate(ctx)
+	return p.report, err
+}
@@ -0,0 +1,324 @@
+package surgeon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bketelsen/surgeon/codemods"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// newUpstream creates a repository with files committed to it and returns
+// its location and the commit
+func newUpstream(t *testing.T, files map[string]string) (string, string) {
+	dir := t.TempDir()
+	_, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	return dir, commitFiles(t, dir, files)
+}
+
+// commitFiles writes files to the repository in dir, removes the files in
+// removed and commits the result. It returns the commit.
+func commitFiles(t *testing.T, dir string, files map[string]string, removed ...string) string {
+	r, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		_, err = w.Add(name)
+		require.NoError(t, err)
+	}
+	for _, name := range removed {
+		_, err = w.Remove(name)
+		require.NoError(t, err)
+	}
+	hash, err := w.Commit("Change files", &git.CommitOptions{
+		Author: &object.Signature{Name: "Upstream", Email: "upstream@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash.String()
+}
+
+// syncedLock returns the lock file of a fork synced with the upstream
+// commit by the codemods of c
+func syncedLock(t *testing.T, c Config, commit string) string {
+	mods, err := newPatient(c, Options{ForkRoot: t.TempDir()}).codeModsHash(commit)
+	require.NoError(t, err)
+	bb, err := yaml.Marshal(Lock{Upstream: c.Upstream, Commit: commit, Synced: &Synced{Commit: commit, CodeMods: mods}})
+	require.NoError(t, err)
+	return string(bb)
+}
+
+// commitLink creates the symbolic link name to target in the repository
+// in dir, replacing an existing one, and commits it. It returns the commit.
+func commitLink(t *testing.T, dir, name, target string) string {
+	r, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.RemoveAll(path))
+	require.NoError(t, os.Symlink(target, path))
+	_, err = w.Add(name)
+	require.NoError(t, err)
+	hash, err := w.Commit("Link "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Upstream", Email: "upstream@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash.String()
+}
+
+func TestRun(t *testing.T) {
+	upstream, commit := newUpstream(t, map[string]string{
+		"install.sh": "curl https://upstream/raw/main/install.sh\n",
+		"README.md":  "upstream\n",
+	})
+	fork := t.TempDir()
+	_, err := git.PlainClone(fork, false, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{ID: "urls", Mod: "sed", Match: []string{"*.sh"}, Args: []string{"upstream", "fork"}}},
+		Commit:   Commit{Enabled: true, AuthorName: "Fork", AuthorEmail: "fork@example.com"},
+	}
+	var out bytes.Buffer
+	opts := Options{
+		ForkRoot: fork,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      &out,
+	}
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, commit, report.Commit)
+	require.Len(t, report.CodeMods, 1)
+	assert.Equal(t, "urls", report.CodeMods[0].ID)
+	assert.Equal(t, 1, report.CodeMods[0].Changed)
+	assert.Equal(t, []string{"install.sh"}, report.Copied)
+	assert.NotEmpty(t, report.Committed)
+	assert.Contains(t, out.String(), "Committed "+report.Committed)
+
+	bb, err := os.ReadFile(filepath.Join(fork, "install.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "curl https://fork/raw/main/install.sh\n", string(bb))
+	l, err := ReadLock(fork)
+	require.NoError(t, err)
+	assert.Equal(t, commit, l.Commit)
+
+	// a failing codemod still reports the codemods that ran
+	c.CodeMods = append(c.CodeMods, CodeMod{Mod: "sed", Match: []string{"*.md"}, Args: []string{"missing", "x"}, Expect: Expect{Changes: ChangesRequired}})
+	report, err = Run(context.Background(), c, opts)
+	assert.ErrorContains(t, err, "changed none")
+	require.NotNil(t, report)
+	require.Len(t, report.CodeMods, 2)
+	assert.NotEmpty(t, report.CodeMods[1].Error)
+}
+
+func TestRunUpstreamRef(t *testing.T) {
+	upstream, pinned := newUpstream(t, map[string]string{"install.sh": "echo upstream 1\n"})
+	r, err := git.PlainOpen(upstream)
+	require.NoError(t, err)
+	hash := plumbing.NewHash(pinned)
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("stable"), hash)))
+	_, err = r.CreateTag("v1", hash, nil)
+	require.NoError(t, err)
+	_, err = r.CreateTag("v1.1", hash, &git.CreateTagOptions{
+		Message: "v1.1",
+		Tagger:  &object.Signature{Name: "Upstream", Email: "upstream@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	head := commitFiles(t, upstream, map[string]string{"install.sh": "echo upstream 2\n"})
+
+	fork := t.TempDir()
+	_, err = git.PlainClone(fork, false, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	opts := Options{
+		ForkRoot: fork,
+		DryRun:   true,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      io.Discard,
+	}
+
+	tests := []struct {
+		name   string
+		ref    string
+		commit string
+	}{
+		{name: "default branch", commit: head},
+		{name: "branch", ref: "stable", commit: pinned},
+		{name: "lightweight tag", ref: "v1", commit: pinned},
+		{name: "annotated tag", ref: "v1.1", commit: pinned},
+		{name: "commit", ref: pinned, commit: pinned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Run(context.Background(), Config{Upstream: upstream, UpstreamRef: tt.ref}, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.commit, report.Commit)
+		})
+	}
+
+	_, err = Run(context.Background(), Config{Upstream: upstream, UpstreamRef: "missing"}, opts)
+	assert.ErrorContains(t, err, `resolving upstream ref "missing"`)
+}
+
+func TestRunLocked(t *testing.T) {
+	upstream, locked := newUpstream(t, map[string]string{"install.sh": "echo upstream 1\n"})
+	// the fork pulls from its own origin, which upstream commits do not reach
+	origin := t.TempDir()
+	_, err := git.PlainClone(origin, true, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	fork := t.TempDir()
+	_, err = git.PlainClone(fork, false, &git.CloneOptions{URL: origin})
+	require.NoError(t, err)
+	c := Config{Upstream: upstream}
+	var out bytes.Buffer
+	opts := Options{
+		ForkRoot: fork,
+		Locked:   true,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      &out,
+	}
+
+	_, err = Run(context.Background(), c, opts)
+	assert.ErrorContains(t, err, "run 'surgeon update' first")
+
+	// update pins the upstream commit, later upstream commits are not synced
+	l, err := Update(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, locked, l.Commit)
+	assert.Nil(t, l.Synced)
+	assert.Contains(t, out.String(), "Locked upstream at "+locked)
+	commitLock(t, opts)
+	head := commitFiles(t, upstream, map[string]string{"install.sh": "echo upstream 2\n"})
+
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, locked, report.Commit)
+	assert.Equal(t, "echo upstream 1\n", readForkString(t, opts, "install.sh"))
+
+	// updating again moves the lock to the new commit
+	out.Reset()
+	l, err = Update(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, head, l.Commit)
+	assert.Contains(t, out.String(), "Updated upstream from "+locked+" to "+head)
+	out.Reset()
+	_, err = Update(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "is up to date at "+head)
+
+	// the lock is for the upstream of the configuration only
+	commitLock(t, opts)
+	_, err = Run(context.Background(), Config{Upstream: t.TempDir()}, opts)
+	assert.ErrorContains(t, err, "is for upstream")
+}
+
+func TestApplyMods(t *testing.T) {
+	mods := []CodeMod{
+		{Mod: "sed", Match: []string{"*.func"}, Args: []string{"upstream", "fork"}},
+		{Mod: "sed", Match: []string{"*.func"}, Args: []string{"fork main", "fork dev"}},
+	}
+	opts := Options{ForkRoot: t.TempDir(), Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	upstream := codemods.MemFS{"build.func": {Data: []byte("echo upstream main\n"), Mode: 0o755}}
+
+	// a failing codemod reports the codemods that ran
+	failing := append(mods, CodeMod{Mod: "sed", Match: []string{"*.func"}, Args: []string{"missing", "x"}, Expect: Expect{Changes: ChangesRequired}})
+	results, err := newPatient(Config{CodeMods: failing}, opts).applyMods(context.Background(), codemods.NewTree(upstream), "")
+	require.Error(t, err)
+	assert.Len(t, results, 3)
+
+	// each codemod sees the changes of the ones before it, and the
+	// upstream files are left untouched
+	var logs bytes.Buffer
+	opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	tree := codemods.NewTree(upstream)
+	results, err = newPatient(Config{CodeMods: mods}, opts).applyMods(context.Background(), tree, "")
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `msg="Applying sed"`, "codemods log to the logger of the options")
+	require.Len(t, results, 2)
+	assert.Equal(t, 1, results[1].Changed)
+	assert.Equal(t, []string{"build.func"}, tree.Changed())
+	bb, err := tree.ReadFile("build.func")
+	require.NoError(t, err)
+	assert.Equal(t, "echo fork dev\n", string(bb))
+	fi, err := tree.Stat("build.func")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), fi.Mode().Perm())
+	assert.Equal(t, "echo upstream main\n", string(upstream["build.func"].Data))
+}
+
+func TestRunSymlink(t *testing.T) {
+	upstream, _ := newUpstream(t, map[string]string{
+		"install.sh": "echo install\n",
+	})
+	// the fork pulls from its own origin, so it starts without the link
+	origin := t.TempDir()
+	_, err := git.PlainClone(origin, true, &git.CloneOptions{URL: upstream})
+	require.NoError(t, err)
+	fork := t.TempDir()
+	_, err = git.PlainClone(fork, false, &git.CloneOptions{URL: origin})
+	require.NoError(t, err)
+	commitLink(t, upstream, "latest.sh", "install.sh")
+
+	c := Config{
+		Upstream: upstream,
+		Commit:   Commit{Enabled: true, AuthorName: "Fork", AuthorEmail: "fork@example.com"},
+	}
+	opts := Options{
+		ForkRoot: fork,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      io.Discard,
+	}
+	assertLink := func() {
+		t.Helper()
+		link, err := os.Readlink(filepath.Join(fork, "latest.sh"))
+		require.NoError(t, err, "latest.sh is a symbolic link")
+		assert.Equal(t, "install.sh", link)
+	}
+
+	var plan bytes.Buffer
+	dryRun := opts
+	dryRun.DryRun, dryRun.Out = true, &plan
+	_, err = Run(context.Background(), c, dryRun)
+	require.NoError(t, err)
+	assert.Contains(t, plan.String(), "new file mode 120000\n")
+
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest.sh"}, report.Copied)
+	assert.NotEmpty(t, report.Committed)
+	assertLink()
+	bb, err := os.ReadFile(filepath.Join(fork, "install.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "echo install\n", string(bb), "the link target is left alone")
+
+	// an unchanged link is not copied again
+	report, err = Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.Copied)
+	assert.Empty(t, report.Conflicted)
+	assertLink()
+	clean, err := newPatient(Config{}, opts).isClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+}
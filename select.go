@@ -0,0 +1,58 @@
+package surgeon
+
+import (
+	"fmt"
+	"slices"
+)
+
+// selected reports whether mod runs with the --only, --skip and --tags
+// selection. --only and --skip take codemod ids or codemod names, and a
+// codemod disabled in the configuration only runs if --only names it.
+func (p *patient) selected(mod CodeMod) bool {
+	named := func(names []string) bool {
+		return slices.Contains(names, mod.Mod) || (mod.ID != "" && slices.Contains(names, mod.ID))
+	}
+	switch {
+	case len(p.Only) > 0 && !named(p.Only):
+		return false
+	case len(p.Only) == 0 && !mod.IsEnabled():
+		return false
+	case named(p.Skip):
+		return false
+	case len(p.Tags) > 0:
+		return slices.ContainsFunc(mod.Tags, func(tag string) bool {
+			return slices.Contains(p.Tags, tag)
+		})
+	}
+	return true
+}
+
+// checkSelection reports --only and --skip values that name no codemod,
+// which are most likely typos
+func (p *patient) checkSelection() error {
+	for _, name := range slices.Concat(p.Only, p.Skip) {
+		known := slices.ContainsFunc(p.Config.CodeMods, func(mod CodeMod) bool {
+			return mod.Mod == name || mod.ID == name
+		})
+		if !known {
+			return fmt.Errorf("no codemod with id or name %q", name)
+		}
+	}
+	return nil
+}
+
+// duplicateIDs returns the codemod ids used more than once
+func duplicateIDs(mods []CodeMod) []string {
+	seen := map[string]bool{}
+	var dups []string
+	for _, mod := range mods {
+		if mod.ID == "" {
+			continue
+		}
+		if seen[mod.ID] && !slices.Contains(dups, mod.ID) {
+			dups = append(dups, mod.ID)
+		}
+		seen[mod.ID] = true
+	}
+	return dups
+}
@@ -0,0 +1,67 @@
+package surgeon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelected(t *testing.T) {
+	off := false
+	mods := []CodeMod{
+		{ID: "urls", Mod: "sed", Tags: []string{"branding"}},
+		{ID: "module", Mod: "gomodule", Tags: []string{"go"}},
+		{Mod: "sed", Tags: []string{"branding", "go"}},
+		{ID: "experimental", Mod: "regex", Enabled: &off},
+	}
+	tests := []struct {
+		name     string
+		only     []string
+		skip     []string
+		tags     []string
+		expected []bool
+	}{
+		{name: "Default", expected: []bool{true, true, true, false}},
+		{name: "Only id", only: []string{"module"}, expected: []bool{false, true, false, false}},
+		{name: "Only name", only: []string{"sed"}, expected: []bool{true, false, true, false}},
+		{name: "Only disabled", only: []string{"experimental"}, expected: []bool{false, false, false, true}},
+		{name: "Skip", skip: []string{"urls", "gomodule"}, expected: []bool{false, false, true, false}},
+		{name: "Tags", tags: []string{"go"}, expected: []bool{false, true, true, false}},
+		{name: "Tags and skip", tags: []string{"branding"}, skip: []string{"urls"}, expected: []bool{false, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPatient(Config{CodeMods: mods}, Options{Only: tt.only, Skip: tt.skip, Tags: tt.tags})
+			var selected []bool
+			for _, mod := range mods {
+				selected = append(selected, p.selected(mod))
+			}
+			assert.Equal(t, tt.expected, selected)
+			assert.NoError(t, p.checkSelection())
+		})
+	}
+}
+
+func TestCheckSelection(t *testing.T) {
+	p := newPatient(Config{CodeMods: []CodeMod{{ID: "urls", Mod: "sed"}}}, Options{Skip: []string{"url"}})
+	assert.Error(t, p.checkSelection())
+	assert.Equal(t, []string{"a"}, duplicateIDs([]CodeMod{{ID: "a"}, {ID: "b"}, {ID: "a"}, {ID: "a"}, {}}))
+}
+
+func TestCheckConfigSelected(t *testing.T) {
+	c := Config{CodeMods: []CodeMod{
+		{ID: "broken", Mod: "nope", Match: []string{"*"}, Tags: []string{"wip"}},
+		{ID: "off", Mod: "sed", Match: []string{"*"}, Enabled: new(bool)},
+		{ID: "urls", Mod: "sed", Match: []string{"*"}, Args: []string{"a", "b"}, Tags: []string{"core"}},
+	}}
+	opts := Options{ForkRoot: t.TempDir()}
+	assert.Len(t, Check(c, opts), 2)
+	assert.Error(t, newPatient(c, opts).checkConfig())
+
+	// problems of codemods excluded from the run are left to surgeon validate
+	opts.Skip = []string{"broken"}
+	assert.NoError(t, newPatient(c, opts).checkConfig())
+	opts.Skip = nil
+	opts.Tags = []string{"core"}
+	assert.NoError(t, newPatient(c, opts).checkConfig())
+}
@@ -26,20 +26,87 @@ THE SOFTWARE.
 // file and can be applied to the source code using the surgeon tool.
 package surgeon
 
+import "time"
+
+// Config is the configuration of a fork. Codemods and ignorelist entries
+// can also come from fragments, files listed in Include and the
+// *.surgeon.yaml files in ModsDir, which only contain those two sections
+// and Include.
 type Config struct {
-	Upstream   string
-	ModsDir    string    `mapstructure:"modsdir"`
-	CodeMods   []CodeMod `mapstructure:"codemods"`
-	IgnoreList []Ignore  `mapstructure:"ignorelist"`
+	Upstream    string
+	UpstreamRef string            `mapstructure:"upstream_ref" yaml:"upstream_ref,omitempty"` // branch, tag or commit
+	ModsDir     string            `mapstructure:"modsdir"`
+	CodeMods    []CodeMod         `mapstructure:"codemods"`
+	IgnoreList  []Ignore          `mapstructure:"ignorelist"`
+	Commit      Commit            `mapstructure:"commit" yaml:"commit,omitempty"`
+	Vars        map[string]string `mapstructure:"vars" yaml:"vars,omitempty"`       // expanded by {{ .vars.name }} in codemod match patterns and arguments
+	Include     []string          `mapstructure:"include" yaml:"include,omitempty"` // fragments to merge, relative to this file
+}
+
+// Commit configures the optional commit of the sync results to the fork.
+// The author defaults to the user.name and user.email of the fork's git config.
+type Commit struct {
+	Enabled     bool
+	Branch      string   // create and switch to this branch before committing
+	AuthorName  string   `mapstructure:"author_name" yaml:"author_name,omitempty"`
+	AuthorEmail string   `mapstructure:"author_email" yaml:"author_email,omitempty"`
+	Trailers    []string // e.g. "Signed-off-by: Jane Doe <jane@example.com>"
+}
+
+// Lock records the upstream commit to sync and what the last successful
+// run wrote to the fork. It is stored in .surgeon.lock in the root of the
+// fork.
+type Lock struct {
+	Upstream string  `yaml:"upstream"`
+	Ref      string  `yaml:"ref,omitempty"`
+	Commit   string  `yaml:"commit"`           // upstream commit to sync, moved by surgeon update
+	Synced   *Synced `yaml:"synced,omitempty"` // nil until surgeon synced the fork
+}
+
+// Synced records the last sync of the fork, the base fork edits are
+// detected against by the next one
+type Synced struct {
+	Commit    string            `yaml:"commit"`              // upstream commit the fork was synced with
+	CodeMods  string            `yaml:"codemods"`            // hash of the codemods applied to it
+	Conflicts map[string]string `yaml:"conflicts,omitempty"` // unresolved fork files, by the hash of their content
 }
 
+// Ignore excludes upstream files from the sync. Prefix ignores every path
+// starting with it, Pattern takes a gitignore pattern with globs, '**',
+// a trailing '/' for directories and '!' to re-include a path.
 type Ignore struct {
-	Prefix string
+	Prefix  string `yaml:"prefix,omitempty"`
+	Pattern string `yaml:"pattern,omitempty"`
 }
 
 type CodeMod struct {
+	ID          string `yaml:"id,omitempty"` // names the codemod for --only and --skip
 	Description string
 	Mod         string
-	Match       string // glob https://pkg.go.dev/path/filepath#Glob
+	Tags        []string `yaml:"tags,omitempty"`    // select codemods with --tags
+	Enabled     *bool    `yaml:"enabled,omitempty"` // false skips the codemod unless --only names it
+	Match       []string // doublestar globs, '!' excludes https://pkg.go.dev/github.com/bmatcuk/doublestar/v4#Match
 	Args        []string
+	With        map[string]any `yaml:"with,omitempty"` // arguments by name, see 'surgeon codemod describe'
+	Expect      Expect         `yaml:"expect,omitempty"`
+	Timeout     time.Duration  `yaml:"timeout,omitempty"` // stop the codemod after this long, e.g. 30s
+}
+
+// IsEnabled reports whether the codemod runs by default
+func (m CodeMod) IsEnabled() bool {
+	return m.Enabled == nil || *m.Enabled
 }
+
+// Expect describes what a codemod has to do for the sync to succeed.
+// Without it, a codemod that matches or changes no files only logs a warning.
+type Expect struct {
+	Changes  string `yaml:"changes,omitempty"`                            // required or optional
+	MinFiles int    `mapstructure:"min_files" yaml:"min_files,omitempty"` // fewest matched files
+	MaxFiles int    `mapstructure:"max_files" yaml:"max_files,omitempty"` // most matched files, 0 for no limit
+}
+
+// expected changes of a codemod
+const (
+	ChangesRequired = "required" // fail if no file changes
+	ChangesOptional = "optional" // files may already be up to date
+)
@@ -0,0 +1,267 @@
+package surgeon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bketelsen/surgeon/codemods"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Conflict handling modes for files changed in both the fork and upstream
+const (
+	ConflictRefuse  = "refuse"  // fail without touching the fork
+	ConflictMarkers = "markers" // write the file with conflict markers
+)
+
+// change is a planned modification of a single fork file
+type change struct {
+	path     string
+	content  []byte // nil removes the file
+	mode     fs.FileMode
+	merged   bool // content is a three-way merge of fork and upstream
+	conflict bool // fork and upstream changes could not be merged
+}
+
+// prepareBase reads the upstream commit recorded in the lock file and
+// applies the codemods to it, giving the version of every file the last
+// sync wrote to the fork. Without it, fork edits cannot be detected and
+// upstream files overwrite the fork.
+func (p *patient) prepareBase(ctx context.Context) error {
+	l, err := ReadLock(p.ForkRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			p.log.Warn("No lock file, fork edits will be overwritten by upstream changes")
+			return nil
+		}
+		return err
+	}
+	if l.Upstream != p.Config.Upstream {
+		p.log.Warn("Lock file is for another upstream, fork edits will be overwritten", "upstream", l.Upstream)
+		return nil
+	}
+	if l.Synced == nil {
+		p.log.Warn("The fork was never synced, fork edits will be overwritten by upstream changes")
+		return nil
+	}
+	synced := l.Synced.Commit
+	p.lastSynced = synced
+	p.unresolved = l.Synced.Conflicts
+	commit, err := p.upstreamRepo.CommitObject(plumbing.NewHash(synced))
+	if err != nil {
+		p.log.Warn("Last synced upstream commit not available, fork edits will be overwritten", "commit", synced, "error", err)
+		return nil
+	}
+	// the base is rebuilt with the codemods of this run, which only gives
+	// what the last sync wrote if they did not change since
+	mods, err := p.codeModsHash(synced)
+	if err != nil || mods != l.Synced.CodeMods {
+		p.log.Warn("Codemods changed since the last sync, fork edits will be overwritten by upstream changes")
+		return nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("reading last synced upstream: %w", err)
+	}
+	p.log.Info("Preparing last synced upstream", "commit", synced)
+	p.base = codemods.NewTree(codemods.GitFS(tree))
+	_, err = p.applyMods(ctx, p.base, synced)
+	if err != nil {
+		// the unmodified files still allow a merge, it is just less precise
+		p.log.Warn("Codemods do not apply to the last synced upstream, using it unmodified", "error", err)
+		p.base = codemods.NewTree(codemods.GitFS(tree))
+	}
+	return nil
+}
+
+// resolve decides what happens to every planned file. Files changed only
+// upstream take the upstream version, files changed only in the fork keep
+// the fork version, and files changed on both sides are merged.
+func (p *patient) resolve() ([]change, error) {
+	paths := make([]string, 0, len(p.plan))
+	for path := range p.plan {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var changes []change
+	for _, path := range paths {
+		c, err := p.resolveFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", path, err)
+		}
+		if c != nil {
+			changes = append(changes, *c)
+		}
+	}
+	return changes, nil
+}
+
+func (p *patient) resolveFile(path string) (*change, error) {
+	theirs, mode, err := readIfExists(p.upstream, path)
+	if err != nil {
+		return nil, err
+	}
+	ours, oursMode, err := readForkFile(p.ForkRoot, path)
+	if err != nil {
+		return nil, err
+	}
+	var base []byte
+	if p.base != nil {
+		base, _, err = readIfExists(p.base, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case theirs == nil && ours == nil:
+		return nil, nil
+	case p.unresolved[path] != "" && p.unresolved[path] == contentHash(ours):
+		// left as it was after a conflict of the last sync
+		p.log.Warn("Unresolved conflict", "file", path)
+		return &change{path: path, content: ours, conflict: true}, nil
+	case theirs != nil && ours != nil && bytes.Equal(theirs, ours) && mode.Type() == oursMode.Type():
+		return nil, nil
+	case ours == nil || p.base == nil || (base != nil && bytes.Equal(ours, base)):
+		// the fork has not touched the file since the last sync
+		return &change{path: path, content: theirs, mode: mode}, nil
+	case theirs == nil && base == nil:
+		// upstream removed the file before the last sync
+		return &change{path: path}, nil
+	case base != nil && theirs != nil && bytes.Equal(theirs, base):
+		p.log.Info("Keeping fork changes", "file", path)
+		return nil, nil
+	case theirs == nil || isBinary(ours) || isBinary(theirs) || isBinary(base) || (mode|oursMode)&fs.ModeSymlink != 0:
+		// deleted upstream but edited in the fork, or not mergeable
+		p.log.Warn("Conflicting changes", "file", path)
+		return &change{path: path, content: ours, conflict: true}, nil
+	}
+
+	merged, conflict := threeWayMerge(base, ours, theirs, "fork", "upstream")
+	if conflict {
+		p.log.Warn("Conflicting changes", "file", path)
+	} else {
+		p.log.Info("Merged fork and upstream changes", "file", path)
+	}
+	return &change{path: path, content: merged, mode: mode, merged: true, conflict: conflict}, nil
+}
+
+// readIfExists reads the file path of fsys, returning nil content if it
+// does not exist. The mode is the permissions of the file, with
+// fs.ModeSymlink set for a symbolic link, whose content is its target.
+func readIfExists(fsys fs.FS, path string) ([]byte, fs.FileMode, error) {
+	fi, err := fs.Stat(fsys, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return content, fi.Mode() & (fs.ModePerm | fs.ModeSymlink), nil
+}
+
+// readForkFile is readIfExists for the file path below root on disk,
+// reading the target of a symbolic link instead of following it
+func readForkFile(root, path string) ([]byte, fs.FileMode, error) {
+	name := filepath.Join(root, filepath.FromSlash(path))
+	fi, err := os.Lstat(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		return readIfExists(codemods.DirFS(root), path)
+	}
+	target, err := os.Readlink(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return []byte(filepath.ToSlash(target)), fi.Mode() & (fs.ModePerm | fs.ModeSymlink), nil
+}
+
+// contentHash returns the git blob hash of content
+func contentHash(content []byte) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, content).String()
+}
+
+// conflicts returns the paths of the changes that could not be merged
+func conflicts(changes []change) []string {
+	var paths []string
+	for _, c := range changes {
+		if c.conflict {
+			paths = append(paths, c.path)
+		}
+	}
+	return paths
+}
+
+// apply writes the changes to the fork
+func (p *patient) apply(changes []change) error {
+	for _, c := range changes {
+		target := filepath.Join(p.ForkRoot, c.path)
+		switch {
+		case c.conflict && !c.merged:
+			// nothing to write, the fork version is kept
+			p.conflicted = append(p.conflicted, c.path)
+			continue
+		case c.content == nil:
+			err := p.removeFile(c.path)
+			if err != nil {
+				return err
+			}
+			p.deleted = append(p.deleted, c.path)
+			continue
+		}
+		p.log.Debug("Writing file", "file", c.path)
+		err := os.MkdirAll(filepath.Dir(target), 0o755)
+		if err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		err = writeForkFile(target, c.content, c.mode)
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", c.path, err)
+		}
+		if c.conflict {
+			p.conflicted = append(p.conflicted, c.path)
+		} else {
+			p.copied = append(p.copied, c.path)
+		}
+	}
+	return nil
+}
+
+// writeForkFile writes content to target with mode. A symbolic link is
+// created again with content as its target, and replaced rather than
+// written through.
+func writeForkFile(target string, content []byte, mode fs.FileMode) error {
+	fi, err := os.Lstat(target)
+	if err == nil && (fi.Mode()|mode)&fs.ModeSymlink != 0 {
+		err = os.Remove(target)
+		if err != nil {
+			return err
+		}
+	}
+	if mode&fs.ModeSymlink != 0 {
+		return os.Symlink(filepath.FromSlash(string(content)), target)
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+	return os.WriteFile(target, content, perm)
+}
@@ -0,0 +1,126 @@
+package surgeon
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSyncedFork clones upstream into a fork pulling from its own origin and
+// commits files to it, along with a lock file recording that the fork was
+// synced with commit by the codemods of c. It returns the options to sync
+// the fork.
+func newSyncedFork(t *testing.T, c Config, commit string, files map[string]string) Options {
+	origin := t.TempDir()
+	_, err := git.PlainClone(origin, true, &git.CloneOptions{URL: c.Upstream})
+	require.NoError(t, err)
+	fork := t.TempDir()
+	_, err = git.PlainClone(fork, false, &git.CloneOptions{URL: origin})
+	require.NoError(t, err)
+	files[LockFile] = syncedLock(t, c, commit)
+	commitFiles(t, fork, files)
+	return Options{
+		ForkRoot: fork,
+		Source:   &Mirror{Dir: t.TempDir()},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Out:      io.Discard,
+	}
+}
+
+// readForkString returns the content of the file name of the fork
+func readForkString(t *testing.T, opts Options, name string) string {
+	bb, err := os.ReadFile(filepath.Join(opts.ForkRoot, name))
+	require.NoError(t, err)
+	return string(bb)
+}
+
+// commitLock commits the lock file of the fork
+func commitLock(t *testing.T, opts Options) {
+	commitFiles(t, opts.ForkRoot, map[string]string{LockFile: readForkString(t, opts, LockFile)})
+}
+
+func TestRunCodeModsChanged(t *testing.T) {
+	upstream, commit := newUpstream(t, map[string]string{"f.sh": "echo upstream a\n"})
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{Mod: "sed", Match: []string{"*.sh"}, Args: []string{"upstream", "fork"}}},
+	}
+	opts := newSyncedFork(t, c, commit, map[string]string{"f.sh": "echo fork a\n"})
+
+	// the base rebuilt with the new codemod would hide it as a fork edit
+	c.CodeMods = append(c.CodeMods, CodeMod{Mod: "sed", Match: []string{"*.sh"}, Args: []string{" a", " b"}})
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f.sh"}, report.Copied)
+	assert.Equal(t, "echo fork b\n", readForkString(t, opts, "f.sh"))
+}
+
+func TestRunUnresolvedConflict(t *testing.T) {
+	upstream, commit := newUpstream(t, map[string]string{
+		"gone.sh": "echo gone\n",
+		"keep.sh": "echo keep\n",
+	})
+	c := Config{Upstream: upstream}
+	opts := newSyncedFork(t, c, commit, map[string]string{"gone.sh": "echo edited\n"})
+	opts.Conflicts = ConflictMarkers
+	commitFiles(t, upstream, nil, "gone.sh")
+
+	// the fork edited a file upstream deleted, which is kept until the
+	// conflict is resolved
+	for i := range 2 {
+		report, err := Run(context.Background(), c, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gone.sh"}, report.Conflicted)
+		assert.Empty(t, report.Deleted)
+		assert.Equal(t, "echo edited\n", readForkString(t, opts, "gone.sh"))
+		l, err := ReadLock(opts.ForkRoot)
+		require.NoError(t, err)
+		assert.Contains(t, l.Synced.Conflicts, "gone.sh")
+		if i == 0 {
+			commitLock(t, opts)
+		}
+	}
+
+	// editing the file again resolves it, keeping the fork version
+	commitFiles(t, opts.ForkRoot, map[string]string{"gone.sh": "echo resolved\n"})
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.Conflicted)
+	l, err := ReadLock(opts.ForkRoot)
+	require.NoError(t, err)
+	assert.Empty(t, l.Synced.Conflicts)
+}
+
+func TestRunUpdateLocked(t *testing.T) {
+	upstream, synced := newUpstream(t, map[string]string{"f.sh": "echo upstream a\n"})
+	c := Config{
+		Upstream: upstream,
+		CodeMods: []CodeMod{{Mod: "sed", Match: []string{"*.sh"}, Args: []string{"upstream", "fork"}}},
+	}
+	opts := newSyncedFork(t, c, synced, map[string]string{"f.sh": "echo fork a\n"})
+	commit := commitFiles(t, upstream, map[string]string{"f.sh": "echo upstream b\n"})
+
+	// update pins the new commit, the fork is still synced with the old one
+	l, err := Update(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, commit, l.Commit)
+	require.NotNil(t, l.Synced)
+	assert.Equal(t, synced, l.Synced.Commit)
+	commitLock(t, opts)
+
+	opts.Locked = true
+	report, err := Run(context.Background(), c, opts)
+	require.NoError(t, err)
+	assert.Equal(t, commit, report.Commit)
+	assert.Equal(t, "echo fork b\n", readForkString(t, opts, "f.sh"))
+	l, err = ReadLock(opts.ForkRoot)
+	require.NoError(t, err)
+	assert.Equal(t, commit, l.Synced.Commit)
+}
@@ -0,0 +1,139 @@
+package surgeon
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// templateData returns the values available to templates in codemod match
+// patterns and arguments. commit is the upstream commit the codemods are
+// applied to, empty if it is not known yet.
+func (p *patient) templateData(commit string) map[string]any {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	vars := p.Config.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	fork := map[string]string{"owner": "", "name": "", "url": ""}
+	repo := p.forkRepo
+	if repo == nil {
+		repo, _ = git.PlainOpen(p.ForkRoot)
+	}
+	if repo != nil {
+		if remote, err := repo.Remote("origin"); err == nil && len(remote.Config().URLs) > 0 {
+			fork["url"] = remote.Config().URLs[0]
+			fork["owner"], fork["name"] = repoOwnerName(fork["url"])
+		}
+	}
+
+	return map[string]any{
+		"vars": vars,
+		"env":  env,
+		"fork": fork,
+		"upstream": map[string]string{
+			"url":    p.Config.Upstream,
+			"ref":    p.Config.UpstreamRef,
+			"commit": commit,
+			"short":  shortHash(commit),
+		},
+	}
+}
+
+// repoOwnerName returns the last two path elements of a git remote URL,
+// e.g. bketelsen and surgeon for git@github.com:bketelsen/surgeon.git
+func repoOwnerName(url string) (string, string) {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	} else if _, rest, ok := strings.Cut(url, ":"); ok && !strings.HasPrefix(url, "/") {
+		url = rest // scp-like syntax
+	}
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	dir, name := path.Split(url)
+	return path.Base(strings.TrimSuffix(dir, "/")), name
+}
+
+// expand executes s as a template with data, failing on missing values.
+// GitHub Actions expressions like ${{ github.sha }} are not templates and
+// are left as they are.
+func expand(s string, data map[string]any) (string, error) {
+	var sb strings.Builder
+	for {
+		before, rest, found := strings.Cut(s, "${{")
+		out, err := expandTemplate(before, data)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(out)
+		if !found {
+			return sb.String(), nil
+		}
+		expr, after, closed := strings.Cut(rest, "}}")
+		if !closed {
+			sb.WriteString("${{" + rest)
+			return sb.String(), nil
+		}
+		sb.WriteString("${{" + expr + "}}")
+		s = after
+	}
+}
+
+// expandTemplate executes s as a template with data
+func expandTemplate(s string, data map[string]any) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	err = t.Execute(&sb, data)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// expandCodeMod returns mod with the templates in its match patterns and
+// arguments expanded, and a problem for each of them that fails
+func expandCodeMod(mod CodeMod, data map[string]any) (CodeMod, []modProblem) {
+	var problems []modProblem
+	expandAll := func(key string, values []string) []string {
+		out := make([]string, len(values))
+		for i, v := range values {
+			s, err := expand(v, data)
+			if err != nil {
+				problems = append(problems, modProblem{key, fmt.Errorf("expanding %q: %w", v, err)})
+			}
+			out[i] = s
+		}
+		return out
+	}
+	mod.Match = expandAll("match", mod.Match)
+	mod.Args = expandAll("args", mod.Args)
+	if mod.With != nil {
+		names := slices.Sorted(maps.Keys(mod.With))
+		with := make(map[string]any, len(mod.With))
+		for _, k := range names {
+			v := mod.With[k]
+			if s, ok := v.(string); ok {
+				v = expandAll("with", []string{s})[0]
+			}
+			with[k] = v
+		}
+		mod.With = with
+	}
+	return mod, problems
+}
@@ -0,0 +1,86 @@
+package surgeon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoOwnerName(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		name  string
+	}{
+		{url: "https://github.com/bketelsen/IncusScripts.git", owner: "bketelsen", name: "IncusScripts"},
+		{url: "https://github.com/bketelsen/IncusScripts/", owner: "bketelsen", name: "IncusScripts"},
+		{url: "git@github.com:bketelsen/surgeon.git", owner: "bketelsen", name: "surgeon"},
+		{url: "ssh://git@example.com:2222/group/sub/repo.git", owner: "sub", name: "repo"},
+		{url: "/srv/git/forks/repo.git", owner: "forks", name: "repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, name := repoOwnerName(tt.url)
+			assert.Equal(t, tt.owner, owner)
+			assert.Equal(t, tt.name, name)
+		})
+	}
+}
+
+func TestExpandCodeMod(t *testing.T) {
+	t.Setenv("SURGEON_TEST_BRANCH", "main")
+	p := newPatient(Config{
+		Upstream: "https://github.com/community-scripts/ProxmoxVE",
+		Vars:     map[string]string{"fork_raw": "https://github.com/bketelsen/IncusScripts/raw/main"},
+	}, Options{ForkRoot: t.TempDir()})
+	data := p.templateData("0123456789abcdef")
+
+	_, problems := expandCodeMod(CodeMod{
+		Mod:   "sed",
+		Match: []string{"{{ .vars.missing_dir }}/*.sh"},
+	}, data)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "match", problems[0].key)
+
+	mod, problems := expandCodeMod(CodeMod{
+		Mod:   "sed",
+		Match: []string{"misc/*.func"},
+		Args:  []string{"https://raw.githubusercontent.com/community-scripts/ProxmoxVE/{{ .env.SURGEON_TEST_BRANCH }}", "{{ .vars.fork_raw }}"},
+		With:  map[string]any{"note": "synced {{ .upstream.short }}", "count": 2},
+	}, data)
+	require.Empty(t, problems)
+	assert.Equal(t, []string{"misc/*.func"}, mod.Match)
+	assert.Equal(t, []string{"https://raw.githubusercontent.com/community-scripts/ProxmoxVE/main", "https://github.com/bketelsen/IncusScripts/raw/main"}, mod.Args)
+	assert.Equal(t, map[string]any{"note": "synced 0123456", "count": 2}, mod.With)
+}
+
+func TestExpand(t *testing.T) {
+	data := map[string]any{"vars": map[string]string{"branch": "main"}}
+	tests := []struct {
+		name     string
+		s        string
+		expected string
+	}{
+		{name: "Plain text", s: "misc/*.func", expected: "misc/*.func"},
+		{name: "Template", s: "ref: {{ .vars.branch }}", expected: "ref: main"},
+		{name: "Actions expression", s: "sha: ${{ github.sha }}", expected: "sha: ${{ github.sha }}"},
+		{
+			name:     "Template and actions expression",
+			s:        "${{ github.ref }} {{ .vars.branch }} ${{ env.X }}",
+			expected: "${{ github.ref }} main ${{ env.X }}",
+		},
+		{name: "Unclosed actions expression", s: "${{ github.sha", expected: "${{ github.sha"},
+		{name: "Escaped braces", s: `{{ "{{" }} .vars.branch }}`, expected: "{{ .vars.branch }}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := expand(tt.s, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, s)
+		})
+	}
+
+	_, err := expand("${{ github.sha }} {{ .vars.missing }}", data)
+	assert.Error(t, err)
+}